@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/synth"
+)
+
+const (
+	defaultMusic = "overworld" // レベルファイルで "music" を省いたときの曲
+	hurryTime    = 40          // 残り時間がこの秒数になったら BGM を速くする
+	hurryTempo   = 1.5         // 残り時間が少ないときのテンポの倍率
+)
+
+// 各パートの音色
+var (
+	leadEnvelope = synth.Envelope{Attack: 0.005, Decay: 0.1, Sustain: 0.6, Release: 0.05}
+	bassEnvelope = synth.Envelope{Attack: 0.005, Decay: 0.05, Sustain: 0.8, Release: 0.03}
+	drumEnvelope = synth.Envelope{Decay: 0.08}
+)
+
+// musicTracks はレベルファイルの "music" で選べる曲
+var musicTracks = map[string]synth.Song{
+	"overworld": {
+		Tempo: 150,
+		Channels: []synth.Channel{
+			{Wave: synth.Pulse, Duty: 0.25, Volume: 0.8, Envelope: leadEnvelope,
+				MML: "o5 l8 e g > c < g a g e c | d f a f e d c4 | e g > c < b a > c < a g | f e d e c4 r4"},
+			{Wave: synth.Triangle, Volume: 1, Envelope: bassEnvelope,
+				MML: "o3 l4 c g c g | f a g g | c e f g | f g c r"},
+			{Wave: synth.Noise, Volume: 0.4, Gate: 0.5, Envelope: drumEnvelope,
+				MML: "l8 [o2 c r o7 c r]8"},
+		},
+	},
+	"underground": {
+		Tempo: 120,
+		Channels: []synth.Channel{
+			{Wave: synth.Square, Volume: 0.6, Envelope: leadEnvelope,
+				MML: "o4 l8 a > c e < a g b > d < g | f a > c < f e4 r4 | a > c e g f e d c | < b > c < b g+ a4 r4"},
+			{Wave: synth.Triangle, Volume: 1, Envelope: bassEnvelope,
+				MML: "o2 l4 a a g g | f f e e | a a f d | e e a r"},
+			{Wave: synth.Noise, Volume: 0.3, Gate: 0.5, Envelope: drumEnvelope,
+				MML: "l4 [o2 c o7 c]8"},
+		},
+	},
+	"castle": {
+		Tempo: 140,
+		Channels: []synth.Channel{
+			{Wave: synth.Pulse, Duty: 0.125, Volume: 0.7, Envelope: leadEnvelope,
+				MML: "o4 l8 d d+ d c+ d f e d+ | d d+ d c+ d a g+ a | d d+ d c+ d f e d+ | d4 a4 d4 r4"},
+			{Wave: synth.Triangle, Volume: 1, Envelope: bassEnvelope,
+				MML: "o2 l8 [d d > d < d]8"},
+			{Wave: synth.Noise, Volume: 0.35, Gate: 0.5, Envelope: drumEnvelope,
+				MML: "l8 [o2 c o7 c c c]8"},
+		},
+	},
+}
+
+// parseMusic はレベルファイルの "music" を曲の名前にする（省略時は defaultMusic）
+func parseMusic(s string) (string, error) {
+	if s == "" {
+		return defaultMusic, nil
+	}
+	if _, ok := musicTracks[s]; !ok {
+		return "", fmt.Errorf("unknown music %q (want overworld, underground or castle)", s)
+	}
+	return s, nil
+}
+
+// playMusic は今のステージの曲を鳴らす（止めていたなら続きから）
+func (g *Game) playMusic() {
+	if g.audioContext == nil {
+		return
+	}
+	if g.bgm == nil {
+		seq, err := synth.NewSequencer(musicTracks[g.music], audioSampleRate)
+		if err != nil {
+			log.Printf("music %s: %v", g.music, err)
+			return
+		}
+		if g.bgm, err = g.audioContext.NewPlayer(seq); err != nil {
+			log.Printf("music %s: %v", g.music, err)
+			return
+		}
+		g.bgmSequencer = seq
+		g.bgm.SetVolume(g.mixer.musicVolume())
+	}
+	g.updateMusicTempo()
+	g.bgm.Play()
+}
+
+// pauseMusic は曲を止める（playMusic で続きから鳴る）
+func (g *Game) pauseMusic() {
+	if g.bgm != nil {
+		g.bgm.Pause()
+	}
+}
+
+// stopMusic は曲を止めて捨てる（次の playMusic で最初から鳴る）
+func (g *Game) stopMusic() {
+	if g.bgm == nil {
+		return
+	}
+	if err := g.bgm.Close(); err != nil {
+		log.Printf("music %s: %v", g.music, err)
+	}
+	g.bgm, g.bgmSequencer = nil, nil
+}
+
+// updateMusicTempo は残り時間が少なければ曲を速くする
+func (g *Game) updateMusicTempo() {
+	if g.bgmSequencer == nil {
+		return
+	}
+	scale := 1.0
+	if g.hurrying() {
+		scale = hurryTempo
+	}
+	g.bgmSequencer.SetTempoScale(scale)
+}
+
+// timeLeftSeconds は HUD に出す残り時間（秒、切り上げ）
+func (g *Game) timeLeftSeconds() int {
+	return (g.timeLeft + 59) / 60
+}
+
+// hurrying は制限時間があって残りが hurryTime 秒以下か
+func (g *Game) hurrying() bool {
+	return g.timeLimit > 0 && g.timeLeft <= hurryTime*60
+}
+
+// updateTimer は残り時間を 1 フレーム減らす。時間切れならやられて true を返す。
+func (g *Game) updateTimer() bool {
+	if g.timeLimit == 0 {
+		return false
+	}
+	g.timeLeft--
+	if g.timeLeft == hurryTime*60 {
+		g.showNotice("HURRY UP!")
+		g.updateMusicTempo()
+	}
+	if g.timeLeft <= 0 {
+		g.showNotice("TIME UP")
+		g.killPlayer()
+		return true
+	}
+	return false
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	bindingsFile    = "bindings.json" // saveStorage / loadStorage の名前
+	bindingsVersion = 1
+	defaultDeadzone = 0.3 // アナログスティックをこれ以上倒したら左右の入力にする
+)
+
+// Control は割り当てを変えられる操作
+type Control int
+
+const (
+	ControlLeft Control = iota
+	ControlRight
+	ControlJump
+	ControlAction
+	ControlPause
+	controlCount
+)
+
+// controlNames はバインド設定ファイルでの操作の名前
+var controlNames = [controlCount]string{"left", "right", "jump", "action", "pause"}
+
+// controlLabels は画面に出す操作の説明
+var controlLabels = [controlCount]string{"left", "right", "jump", "run/fire", "pause"}
+
+// gamepadButtonNames は標準配置のゲームパッドのボタンの名前（設定ファイルと画面表示で使う）
+var gamepadButtonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "LB",
+	ebiten.StandardGamepadButtonFrontTopRight:    "RB",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "LT",
+	ebiten.StandardGamepadButtonFrontBottomRight: "RT",
+	ebiten.StandardGamepadButtonCenterLeft:       "BACK",
+	ebiten.StandardGamepadButtonCenterRight:      "START",
+	ebiten.StandardGamepadButtonLeftStick:        "LS",
+	ebiten.StandardGamepadButtonRightStick:       "RS",
+	ebiten.StandardGamepadButtonLeftTop:          "UP",
+	ebiten.StandardGamepadButtonLeftBottom:       "DOWN",
+	ebiten.StandardGamepadButtonLeftLeft:         "LEFT",
+	ebiten.StandardGamepadButtonLeftRight:        "RIGHT",
+	ebiten.StandardGamepadButtonCenterCenter:     "HOME",
+}
+
+// reservedKeys はホットキーや割り当て画面の操作に使うので、操作に割り当てられないキー
+var reservedKeys = []ebiten.Key{
+	ebiten.KeyF2, ebiten.KeyF3, ebiten.KeyF4, ebiten.KeyF5, ebiten.KeyF6, ebiten.KeyF7, ebiten.KeyF8, ebiten.KeyF9,
+	ebiten.KeyM,
+}
+
+// Bindings は操作ごとのキーとゲームパッドのボタンの割り当て
+type Bindings struct {
+	keys     [controlCount][]ebiten.Key
+	buttons  [controlCount][]ebiten.StandardGamepadButton
+	deadzone float64
+}
+
+// defaultBindings は最初の割り当て（キーボードはこれまでのキー、ゲームパッドは十字キーと A / X ボタン）
+func defaultBindings() *Bindings {
+	return &Bindings{
+		keys: [controlCount][]ebiten.Key{
+			ControlLeft:   {ebiten.KeyArrowLeft, ebiten.KeyA},
+			ControlRight:  {ebiten.KeyArrowRight, ebiten.KeyD},
+			ControlJump:   {ebiten.KeySpace, ebiten.KeyArrowUp, ebiten.KeyW},
+			ControlAction: {ebiten.KeyX, ebiten.KeyShift},
+			ControlPause:  {ebiten.KeyP, ebiten.KeyEscape},
+		},
+		buttons: [controlCount][]ebiten.StandardGamepadButton{
+			ControlLeft:   {ebiten.StandardGamepadButtonLeftLeft},
+			ControlRight:  {ebiten.StandardGamepadButtonLeftRight},
+			ControlJump:   {ebiten.StandardGamepadButtonRightBottom},
+			ControlAction: {ebiten.StandardGamepadButtonRightLeft, ebiten.StandardGamepadButtonRightRight},
+			ControlPause:  {ebiten.StandardGamepadButtonCenterRight},
+		},
+		deadzone: defaultDeadzone,
+	}
+}
+
+// resetControl は操作 c の割り当てを最初の状態に戻す
+func (b *Bindings) resetControl(c Control) {
+	def := defaultBindings()
+	b.keys[c] = def.keys[c]
+	b.buttons[c] = def.buttons[c]
+}
+
+// bindKey は操作 c のキーを key だけにする。ほかの操作に割り当てていた key は外す。
+func (b *Bindings) bindKey(c Control, key ebiten.Key) {
+	for i := range b.keys {
+		b.keys[i] = slices.DeleteFunc(slices.Clone(b.keys[i]), func(k ebiten.Key) bool { return k == key })
+	}
+	b.keys[c] = []ebiten.Key{key}
+}
+
+// bindButton は操作 c のボタンを button だけにする。ほかの操作に割り当てていた button は外す。
+func (b *Bindings) bindButton(c Control, button ebiten.StandardGamepadButton) {
+	for i := range b.buttons {
+		b.buttons[i] = slices.DeleteFunc(slices.Clone(b.buttons[i]), func(bt ebiten.StandardGamepadButton) bool { return bt == button })
+	}
+	b.buttons[c] = []ebiten.StandardGamepadButton{button}
+}
+
+// keyName は画面に出すキーの名前（矢印キーは記号にする）
+func keyName(k ebiten.Key) string {
+	switch k {
+	case ebiten.KeyArrowLeft:
+		return "←"
+	case ebiten.KeyArrowRight:
+		return "→"
+	case ebiten.KeyArrowUp:
+		return "↑"
+	case ebiten.KeyArrowDown:
+		return "↓"
+	}
+	return strings.ToUpper(k.String())
+}
+
+// keyLabel は操作 c に割り当てたキーを "SPACE/↑/W" のようにつなげる（割り当てがなければ "-"）
+func (b *Bindings) keyLabel(c Control) string {
+	names := make([]string, len(b.keys[c]))
+	for i, k := range b.keys[c] {
+		names[i] = keyName(k)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, "/")
+}
+
+// firstKey は操作 c に割り当てた最初のキーの名前（メニューの案内用）
+func (b *Bindings) firstKey(c Control) string {
+	if len(b.keys[c]) == 0 {
+		return "-"
+	}
+	return keyName(b.keys[c][0])
+}
+
+// buttonLabel は操作 c に割り当てたボタンを "X/B" のようにつなげる（割り当てがなければ "-"）
+func (b *Bindings) buttonLabel(c Control) string {
+	names := make([]string, len(b.buttons[c]))
+	for i, bt := range b.buttons[c] {
+		names[i] = gamepadButtonNames[bt]
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, "/")
+}
+
+// describe は HUD に出す操作説明を今の割り当てから作る
+func (b *Bindings) describe() string {
+	parts := make([]string, controlCount)
+	for c := range controlCount {
+		parts[c] = b.keyLabel(c) + " = " + controlLabels[c]
+	}
+	return "Controls: " + strings.Join(parts, ", ") + ", F2 = edit, M = mute"
+}
+
+// bindingsData はバインド設定ファイルの形式。書かれていない操作は最初の割り当てのまま。
+type bindingsData struct {
+	Version  int                 `json:"version"`
+	Keys     map[string][]string `json:"keys"`
+	Buttons  map[string][]string `json:"buttons"`
+	Deadzone float64             `json:"deadzone"`
+}
+
+// encodeBindings は b をバインド設定ファイルの形式にする
+func encodeBindings(b *Bindings) ([]byte, error) {
+	f := bindingsData{
+		Version:  bindingsVersion,
+		Keys:     map[string][]string{},
+		Buttons:  map[string][]string{},
+		Deadzone: b.deadzone,
+	}
+	for c := range controlCount {
+		keys := []string{}
+		for _, k := range b.keys[c] {
+			keys = append(keys, k.String())
+		}
+		buttons := []string{}
+		for _, bt := range b.buttons[c] {
+			buttons = append(buttons, gamepadButtonNames[bt])
+		}
+		f.Keys[controlNames[c]] = keys
+		f.Buttons[controlNames[c]] = buttons
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// decodeBindings はバインド設定ファイルを読む。割り当て画面と同じく、reservedKeys の割り当ては受け付けない。
+func decodeBindings(data []byte) (*Bindings, error) {
+	var f bindingsData
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != bindingsVersion {
+		return nil, fmt.Errorf("unsupported version %d (want %d)", f.Version, bindingsVersion)
+	}
+	b := defaultBindings()
+	if f.Deadzone > 0 && f.Deadzone < 1 {
+		b.deadzone = f.Deadzone
+	}
+	for c := range controlCount {
+		name := controlNames[c]
+		if names, ok := f.Keys[name]; ok {
+			b.keys[c] = nil
+			for _, n := range names {
+				var k ebiten.Key
+				if err := k.UnmarshalText([]byte(n)); err != nil {
+					return nil, fmt.Errorf("keys.%s: %w", name, err)
+				}
+				if slices.Contains(reservedKeys, k) {
+					return nil, fmt.Errorf("keys.%s: %s is reserved for a hotkey", name, n)
+				}
+				b.keys[c] = append(b.keys[c], k)
+			}
+		}
+		if names, ok := f.Buttons[name]; ok {
+			b.buttons[c] = nil
+			for _, n := range names {
+				bt, ok := parseGamepadButton(n)
+				if !ok {
+					return nil, fmt.Errorf("buttons.%s: unknown gamepad button %q", name, n)
+				}
+				b.buttons[c] = append(b.buttons[c], bt)
+			}
+		}
+	}
+	return b, nil
+}
+
+// parseGamepadButton は gamepadButtonNames の名前をボタンに戻す
+func parseGamepadButton(name string) (ebiten.StandardGamepadButton, bool) {
+	for bt, n := range gamepadButtonNames {
+		if n == name {
+			return bt, true
+		}
+	}
+	return 0, false
+}
+
+// loadBindings は保存済みの割り当てを読む。読めなければ最初の割り当てのまま始める。
+func (g *Game) loadBindings() {
+	data, err := loadStorage(bindingsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var b *Bindings
+	if err == nil {
+		b, err = decodeBindings(data)
+	}
+	if err != nil {
+		log.Printf("%s: %v", bindingsFile, err)
+		g.showNotice("BINDINGS LOAD FAILED")
+		return
+	}
+	*g.bindings = *b // deviceInput と同じ Bindings を共有しているので中身を書き換える
+}
+
+// saveBindings は今の割り当てを保存する
+func (g *Game) saveBindings() {
+	data, err := encodeBindings(g.bindings)
+	if err == nil {
+		err = saveStorage(bindingsFile, data)
+	}
+	if err != nil {
+		log.Printf("%s: %v", bindingsFile, err)
+		g.showNotice("BINDINGS SAVE FAILED")
+		return
+	}
+	g.showNotice("BINDINGS SAVED")
+}
+
+// deviceInput はキーボードと、つながっているすべての標準配置のゲームパッドから割り当てに従って入力を読み、
+// 画面のタッチ操作と合わせる
+type deviceInput struct {
+	bindings *Bindings
+	touch    *TouchControls // 画面のタッチ操作（タッチされるまでは何も押していない）
+	gamepads []ebiten.GamepadID
+}
+
+func (d *deviceInput) Next() Input {
+	d.touch.update()
+	pressed := d.touch.pressed
+	b := d.bindings
+	for c := range controlCount {
+		for _, k := range b.keys[c] {
+			pressed[c] = pressed[c] || ebiten.IsKeyPressed(k)
+		}
+	}
+
+	d.gamepads = ebiten.AppendGamepadIDs(d.gamepads[:0])
+	for _, id := range d.gamepads {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for c := range controlCount {
+			for _, bt := range b.buttons[c] {
+				pressed[c] = pressed[c] || ebiten.IsStandardGamepadButtonPressed(id, bt)
+			}
+		}
+		// 左スティックは十字キーと同じ扱い（遊びの範囲は無視する）
+		x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		pressed[ControlLeft] = pressed[ControlLeft] || x <= -b.deadzone
+		pressed[ControlRight] = pressed[ControlRight] || x >= b.deadzone
+	}
+
+	return Input{
+		Left:   pressed[ControlLeft],
+		Right:  pressed[ControlRight],
+		Jump:   pressed[ControlJump],
+		Pause:  pressed[ControlPause],
+		Action: pressed[ControlAction],
+	}
+}
+
+// Rebind は F2 で開く操作の割り当て画面の状態
+type Rebind struct {
+	open     bool
+	selected Control // 選んでいる操作
+	waiting  bool    // 割り当てるキー・ボタンが押されるのを待っている
+	changed  bool    // 閉じるときに保存する
+	keys     []ebiten.Key
+	gamepads []ebiten.GamepadID
+}
+
+// updateRebind は割り当て画面の操作を処理する。画面を開いていてゲームを止めるなら true を返す。
+func (g *Game) updateRebind() bool {
+	r := &g.rebind
+	if !g.hotkeys {
+		return false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF2) {
+		if r.waiting {
+			r.waiting = false // 取り消し
+			return true
+		}
+		r.open = !r.open
+		if !r.open && r.changed {
+			g.saveBindings()
+			r.changed = false
+		}
+		return r.open
+	}
+	if !r.open {
+		return false
+	}
+
+	if r.waiting {
+		r.keys = inpututil.AppendJustPressedKeys(r.keys[:0])
+		for _, k := range r.keys {
+			if !slices.Contains(reservedKeys, k) {
+				g.bindings.bindKey(r.selected, k)
+				r.waiting, r.changed = false, true
+				return true
+			}
+		}
+		r.gamepads = ebiten.AppendGamepadIDs(r.gamepads[:0])
+		for _, id := range r.gamepads {
+			for bt := range gamepadButtonNames {
+				if inpututil.IsStandardGamepadButtonJustPressed(id, bt) {
+					g.bindings.bindButton(r.selected, bt)
+					r.waiting, r.changed = false, true
+					return true
+				}
+			}
+		}
+		return true
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		r.selected = (r.selected + controlCount - 1) % controlCount
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		r.selected = (r.selected + 1) % controlCount
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		r.waiting = true
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		g.bindings.resetControl(r.selected)
+		r.changed = true
+	}
+	return true
+}
+
+// drawRebind は割り当て画面を画面中央に描く
+func (g *Game) drawRebind(screen *ebiten.Image) {
+	r := &g.rebind
+	if !r.open {
+		return
+	}
+	const x, y, w, lineHeight = 150, 150, 500, 16
+	h := float32((int(controlCount) + 7) * lineHeight)
+	vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{A: 200}, false)
+
+	var b strings.Builder
+	b.WriteString("CONTROLS (F2)\n\n")
+	fmt.Fprintf(&b, "  %-9s %-22s %s\n", "", "keyboard", "gamepad")
+	for c := range controlCount {
+		cursor := "  "
+		if c == r.selected {
+			cursor = "> "
+		}
+		fmt.Fprintf(&b, "%s%-9s %-22s %s\n", cursor, controlLabels[c], g.bindings.keyLabel(c), g.bindings.buttonLabel(c))
+	}
+	b.WriteString("\n")
+	if r.waiting {
+		fmt.Fprintf(&b, "press a key or gamepad button for %s\n(F2 = cancel)", controlLabels[r.selected])
+	} else {
+		b.WriteString("↑/↓ select  ENTER rebind  BACKSPACE default\nF2 close and save (left stick also moves)")
+	}
+	ebitenutil.DebugPrintAt(screen, b.String(), x+8, y+4)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestBindingsRoundTrip(t *testing.T) {
+	want := defaultBindings()
+	want.bindKey(ControlJump, ebiten.KeyK)
+	data, err := encodeBindings(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeBindings(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeBindings(encodeBindings(b)) = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeBindingsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // エラーメッセージに含まれる文字列
+	}{
+		{"wrong version", `{"version": 99}`, "unsupported version"},
+		{"unknown key", `{"version": 1, "keys": {"jump": ["NoSuchKey"]}}`, "keys.jump"},
+		{"unknown button", `{"version": 1, "buttons": {"jump": ["Z"]}}`, "buttons.jump"},
+		{"tuning hotkey", `{"version": 1, "keys": {"jump": ["Space", "F4"]}}`, "keys.jump: F4 is reserved"},
+		{"replay hotkey", `{"version": 1, "keys": {"pause": ["F9"]}}`, "keys.pause: F9 is reserved"},
+		{"mute hotkey", `{"version": 1, "keys": {"action": ["M"]}}`, "keys.action: M is reserved"},
+	}
+	for _, tt := range tests {
+		_, err := decodeBindings([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+)
+
+const (
+	bumpFrames      = 12  // 下から叩いたブロックが跳ねるフレーム数
+	bumpHeight      = 8   // ブロックが跳ねる高さ
+	blockCoinScore  = 200 // ハテナブロックから出たコインのスコア
+	brickBreakScore = 50
+	bumpStompScore  = 100 // 叩いたブロックの上にいた敵を倒したときのスコア
+	particleGravity = 0.6
+	coinPopFrames   = 30 // ブロックから飛び出したコインが消えるまでのフレーム数
+	debrisFrames    = 60 // 壊れたレンガの破片が消えるまでのフレーム数
+	coinPopPower    = -9
+	debrisPieceSize = 8
+	debrisJumpPower = -7
+	popCoinRadius   = 10 // ブロックから飛び出すコインの半径
+	debrisSpread    = 2  // 破片が左右に飛ぶ速さ
+)
+
+// BlockContents はハテナブロックから出てくるもの
+type BlockContents int
+
+const (
+	BlockCoin     BlockContents = iota // コインが飛び出す（その場でスコアになる）
+	BlockMushroom                      // キノコが出る。すでに大きければフラワー
+	BlockFlower                        // フラワーが出る
+)
+
+// parseBlockContents はレベルファイルの "contents" をブロックの中身に変換する（省略時は coin）
+func parseBlockContents(s string) (BlockContents, error) {
+	switch s {
+	case "", "coin":
+		return BlockCoin, nil
+	case "mushroom":
+		return BlockMushroom, nil
+	case "flower":
+		return BlockFlower, nil
+	}
+	return 0, fmt.Errorf("unknown block contents %q (want coin, mushroom or flower)", s)
+}
+
+// Particle はブロックから飛び出したコインやレンガの破片（見た目だけで当たり判定はない）
+type Particle struct {
+	x, y   float64
+	vx, vy float64
+	life   int  // 残りフレーム数
+	coin   bool // コイン（false ならレンガの破片）
+}
+
+// bumpOffset はブロックを叩かれて跳ねている分の縦方向のずれ
+func (p *Platform) bumpOffset() float64 {
+	if p.bumpTime == 0 {
+		return 0
+	}
+	return -bumpHeight * math.Sin(math.Pi*float64(p.bumpTime)/bumpFrames)
+}
+
+// spend はブロックを使用済み（ハテナブロック）・壊れた状態（レンガ）にする
+func (p *Platform) spend() {
+	switch p.kind {
+	case PlatformQuestion:
+		p.used = true
+	case PlatformBrick:
+		p.broken = true
+	}
+}
+
+// blockColor はベクター描画で使う足場の色（ブロックは種類ごとの決まった色）
+func (p *Platform) blockColor() color.RGBA {
+	switch {
+	case p.kind == PlatformQuestion && !p.used:
+		return color.RGBA{R: 240, G: 180, B: 40, A: 255}
+	case p.kind == PlatformQuestion:
+		return color.RGBA{R: 140, G: 100, B: 60, A: 255}
+	case p.kind == PlatformBrick:
+		return color.RGBA{R: 180, G: 80, B: 40, A: 255}
+	}
+	return p.color
+}
+
+// bumpBlock はプレイヤーが足場 i に下から頭をぶつけたときの処理。
+// ハテナブロックは中身を出して使用済みになり、レンガは大きい状態なら壊れる。
+// どちらも跳ねて、上に乗っていた敵を倒す。
+func (g *Game) bumpBlock(i int) {
+	p := &g.platforms[i]
+	switch {
+	case p.kind == PlatformQuestion && !p.used:
+		g.releaseContents(p)
+		p.used = true
+	case p.kind == PlatformBrick:
+		if g.player.power != PowerSmall {
+			g.breakBrick(p)
+			g.defeatEnemiesOn(p)
+			return
+		}
+	default:
+		return
+	}
+	p.bumpTime = bumpFrames
+	g.defeatEnemiesOn(p)
+	g.playSound(SoundBump)
+}
+
+// releaseContents はハテナブロック p の中身を出す
+func (g *Game) releaseContents(p *Platform) {
+	if p.contents == BlockCoin {
+		g.score += blockCoinScore
+		g.checkExtraLife()
+		g.particles = append(g.particles, Particle{
+			x: p.x + p.width/2, y: p.y - popCoinRadius, vy: coinPopPower, life: coinPopFrames, coin: true,
+		})
+		g.playSound(SoundCoin)
+		return
+	}
+
+	kind := ItemFlower
+	if p.contents == BlockMushroom && g.player.power == PowerSmall {
+		kind = ItemMushroom
+	}
+	it := Item{
+		kind:     kind,
+		initialX: p.x + (p.width-itemSize)/2,
+		initialY: p.y - itemSize,
+		spawned:  true,
+	}
+	it.reset()
+	g.items = append(g.items, it)
+}
+
+// breakBrick はレンガ p を壊して破片を飛ばす
+func (g *Game) breakBrick(p *Platform) {
+	p.broken = true
+	g.score += brickBreakScore
+	g.checkExtraLife()
+	cx, cy := p.x+p.width/2, p.y+p.height/2
+	for _, d := range [][2]float64{{-1, -1}, {1, -1}, {-1, 0}, {1, 0}} {
+		g.particles = append(g.particles, Particle{
+			x: cx, y: cy, vx: d[0] * debrisSpread, vy: debrisJumpPower + d[1]*3, life: debrisFrames,
+		})
+	}
+	g.playSound(SoundBreak)
+}
+
+// defeatEnemiesOn は足場 p の上に立っている（足元が p の上面にある）敵を倒す
+func (g *Game) defeatEnemiesOn(p *Platform) {
+	probe := collision.Rect{X: p.x, Y: p.y - 2, W: p.width, H: 2}
+	g.nearby = g.enemyGrid.Query(probe, g.nearby[:0])
+	for _, j := range g.nearby {
+		e := &g.enemies[j]
+		if e.isAlive && e.rect().Overlaps(probe) {
+			g.defeatEnemy(e, bumpStompScore)
+		}
+	}
+}
+
+// updateBlocks はブロックの跳ねるアニメーションと飛び出したコイン・破片を進める
+func (g *Game) updateBlocks() {
+	for i := range g.platforms {
+		if g.platforms[i].bumpTime > 0 {
+			g.platforms[i].bumpTime--
+		}
+	}
+
+	live := g.particles[:0]
+	for _, pt := range g.particles {
+		pt.vy += particleGravity
+		pt.x += pt.vx
+		pt.y += pt.vy
+		pt.life--
+		if pt.life > 0 && pt.y < screenHeight {
+			live = append(live, pt)
+		}
+	}
+	g.particles = live
+}
+
+// drawParticles はブロックから飛び出したコインとレンガの破片を描く
+func (g *Game) drawParticles(screen *ebiten.Image, cam float64) {
+	for _, pt := range g.particles {
+		x, y := float32(pt.x-cam), float32(pt.y)
+		if !pt.coin {
+			vector.DrawFilledRect(screen, x-debrisPieceSize/2, y-debrisPieceSize/2, debrisPieceSize, debrisPieceSize,
+				color.RGBA{R: 180, G: 80, B: 40, A: 255}, false)
+			continue
+		}
+		if g.sprites != nil && !g.debugDraw {
+			drawSprite(screen, g.sprites.animFrame("coin-spin", pt.life), pt.x-popCoinRadius-cam, pt.y-popCoinRadius,
+				popCoinRadius*2, popCoinRadius*2, false, nil)
+			continue
+		}
+		vector.DrawFilledCircle(screen, x, y, popCoinRadius, color.RGBA{R: 255, G: 215, A: 255}, false)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+// checkpointPoleHeight は中間地点の旗のポールの高さ
+const checkpointPoleHeight = 60
+
+// updateCheckpoints はプレイヤーが中間地点の旗に触れたかを判定し、
+// 触れたらそこを復活地点にして、その時点のコイン・敵の状態を記録する
+func (g *Game) updateCheckpoints() {
+	for i := range g.checkpoints {
+		cp := &g.checkpoints[i]
+		if cp.isReached {
+			continue
+		}
+		if g.player.x+g.player.width < cp.x || g.player.x > cp.x+8 ||
+			g.player.y+g.player.height < cp.y-checkpointPoleHeight || g.player.y > cp.y {
+			continue
+		}
+		cp.isReached = true
+		g.activeCheckpoint = i
+		g.checkpointFrames = g.elapsedFrames
+
+		g.checkpointCoins = g.checkpointCoins[:0]
+		for _, c := range g.coins {
+			g.checkpointCoins = append(g.checkpointCoins, c.collected)
+		}
+		g.checkpointEnemies = g.checkpointEnemies[:0]
+		for _, e := range g.enemies {
+			g.checkpointEnemies = append(g.checkpointEnemies, !e.isAlive)
+		}
+		g.checkpointItems = g.checkpointItems[:0]
+		for _, it := range g.items {
+			g.checkpointItems = append(g.checkpointItems, !it.active)
+		}
+		g.checkpointBlocks = g.checkpointBlocks[:0]
+		for _, p := range g.platforms {
+			g.checkpointBlocks = append(g.checkpointBlocks, p.used || p.broken)
+		}
+
+		g.showNotice("CHECKPOINT!")
+		g.playSound(SoundCheckpoint)
+	}
+}
+
+// restoreCheckpoint は最後に触れた中間地点から再開する。
+// 中間地点に触れるまでに取ったコイン・アイテム、倒した敵はそのままにし、それ以降のものだけ元に戻す。
+func (g *Game) restoreCheckpoint() {
+	cp := g.checkpoints[g.activeCheckpoint]
+	g.clearTime = 0
+	g.elapsedFrames = g.checkpointFrames
+	g.clearElapsedFrames = 0
+	g.timeLeft = g.timeLimit // 残り時間は中間地点からでも元に戻す
+	g.resetPlayer(cp.x, cp.y-g.player.height)
+	g.cameraX = 0
+	g.resetPlatforms()
+
+	g.goal.isReached = false
+	g.goal.flagHeight = 0
+
+	for i := range g.enemies {
+		g.enemies[i].reset()
+		g.enemies[i].isAlive = !g.checkpointEnemies[i]
+	}
+	g.indexEnemies()
+	for i := range g.coins {
+		g.coins[i].collected = g.checkpointCoins[i]
+	}
+	for i := range g.platforms {
+		if g.checkpointBlocks[i] {
+			g.platforms[i].spend()
+		}
+	}
+	// 中間地点より後にブロックから出たアイテムだけ消す（アイテムは後ろに足していくので、記録した数まで残す）。
+	// 前に出て取っていないアイテムは、ブロックは使用済みのまま出た位置に戻す。
+	g.items = g.items[:len(g.checkpointItems)]
+	for i := range g.items {
+		g.items[i].reset()
+		g.items[i].active = !g.checkpointItems[i]
+	}
+	g.fireballs = g.fireballs[:0]
+	g.particles = g.particles[:0]
+}
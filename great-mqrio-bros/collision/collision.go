@@ -0,0 +1,143 @@
+// Package collision は軸に平行な矩形（AABB）同士の移動と衝突解決を行う。
+//
+// Move は移動量を x → y の順に軸ごとに分けて適用し、それぞれの軸で移動の途中にある
+// 固体の手前で止める（スイープ判定）。移動量が大きいときは MaxStep 以下の小さなステップに
+// 分けて進めるので、落下が速くても薄い足場をすり抜けず、斜めに角をかすめたときも
+// 実際の軌跡に近い面で止まる。上からだけ乗れる足場（Solid.OneWay）にも対応している。
+package collision
+
+import "math"
+
+// MaxStep は 1 ステップで進める最大距離。これより大きい移動はステップに分割する。
+const MaxStep = 8
+
+// epsilon は浮動小数点の誤差で「接している」を「めり込んでいる」と判定しないための余裕
+const epsilon = 1e-6
+
+// Rect は軸に平行な矩形。(X, Y) が左上。
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Right は右端の x 座標
+func (r Rect) Right() float64 { return r.X + r.W }
+
+// Bottom は下端の y 座標
+func (r Rect) Bottom() float64 { return r.Y + r.H }
+
+// Swept は r を (dx, dy) だけ動かす間に通る範囲（移動前と移動後を囲む矩形）
+func (r Rect) Swept(dx, dy float64) Rect {
+	return Rect{
+		X: math.Min(r.X, r.X+dx),
+		Y: math.Min(r.Y, r.Y+dy),
+		W: r.W + math.Abs(dx),
+		H: r.H + math.Abs(dy),
+	}
+}
+
+// Overlaps は r と o が重なっているか（辺が接しているだけなら false）
+func (r Rect) Overlaps(o Rect) bool {
+	return r.X < o.Right() && o.X < r.Right() && r.Y < o.Bottom() && o.Y < r.Bottom()
+}
+
+// Solid は Move で動くものを止める固体
+type Solid struct {
+	Rect
+	OneWay bool // 上からだけ乗れる（下や横からはすり抜ける）足場
+	ID     int  // 呼び出し側で使う識別子（Move は見ない）
+}
+
+// Result は Move の結果
+type Result struct {
+	Rect         // 移動後の位置
+	Floor   bool // 下に動いて固体の上面に乗った
+	Ceiling bool // 上に動いて固体の下面にぶつかった
+	Left    bool // 左に動いて固体の右面にぶつかった
+	Right   bool // 右に動いて固体の左面にぶつかった
+	Ground  int  // 乗った固体の solids での添字（Floor でなければ -1）
+	Head    int  // 頭をぶつけた固体の solids での添字（Ceiling でなければ -1）
+}
+
+// Move は r を (dx, dy) だけ動かし、solids にぶつかった軸はその手前で止める。
+// 移動を始めた時点ですでに重なっている固体は無視する（中から抜け出せるように）。
+func Move(r Rect, dx, dy float64, solids []Solid) Result {
+	res := Result{Rect: r, Ground: -1, Head: -1}
+	steps := int(math.Ceil(math.Max(math.Abs(dx), math.Abs(dy)) / MaxStep))
+	if steps < 1 {
+		steps = 1
+	}
+	sx, sy := dx/float64(steps), dy/float64(steps)
+	for range steps {
+		if sx != 0 {
+			var hit bool
+			res.X, hit = sweepX(res.Rect, sx, solids)
+			if hit {
+				res.Left = res.Left || sx < 0
+				res.Right = res.Right || sx > 0
+				sx = 0 // 壁にぶつかったら残りのステップでは横に動かない
+			}
+		}
+		if sy != 0 {
+			var hit int
+			res.Y, hit = sweepY(res.Rect, sy, solids)
+			if hit >= 0 {
+				if sy > 0 {
+					res.Ground = hit
+				} else {
+					res.Head = hit
+				}
+				res.Floor = res.Floor || sy > 0
+				res.Ceiling = res.Ceiling || sy < 0
+				sy = 0
+			}
+		}
+		if sx == 0 && sy == 0 {
+			break
+		}
+	}
+	return res
+}
+
+// sweepX は r を横に dx 動かしたときの x を返す。途中の固体の手前で止まったら hit。
+func sweepX(r Rect, dx float64, solids []Solid) (x float64, hit bool) {
+	x = r.X + dx
+	for _, s := range solids {
+		if s.OneWay {
+			continue
+		}
+		// 縦の範囲が重なっていない固体には横からぶつからない（上に乗っているだけの足場など）
+		if r.Y >= s.Bottom() || s.Y >= r.Bottom() {
+			continue
+		}
+		if dx > 0 && r.Right() <= s.X+epsilon && x+r.W > s.X {
+			x = s.X - r.W
+			hit = true
+		}
+		if dx < 0 && r.X >= s.Right()-epsilon && x < s.Right() {
+			x = s.Right()
+			hit = true
+		}
+	}
+	return x, hit
+}
+
+// sweepY は r を縦に dy 動かしたときの y を返す。途中の固体の手前で止まったら、
+// その固体の添字を hit に返す（止まらなければ -1）。
+func sweepY(r Rect, dy float64, solids []Solid) (y float64, hit int) {
+	y = r.Y + dy
+	hit = -1
+	for i, s := range solids {
+		if r.X >= s.Right() || s.X >= r.Right() {
+			continue
+		}
+		if dy > 0 && r.Bottom() <= s.Y+epsilon && y+r.H > s.Y {
+			y = s.Y - r.H
+			hit = i
+		}
+		if dy < 0 && !s.OneWay && r.Y >= s.Bottom()-epsilon && y < s.Bottom() {
+			y = s.Bottom()
+			hit = i
+		}
+	}
+	return y, hit
+}
@@ -0,0 +1,161 @@
+package collision
+
+import "testing"
+
+func TestMove(t *testing.T) {
+	player := Rect{X: 0, Y: 0, W: 32, H: 48}
+	at := func(x, y float64) Rect { return Rect{X: x, Y: y, W: 32, H: 48} }
+	solid := func(x, y, w, h float64) Solid { return Solid{Rect: Rect{X: x, Y: y, W: w, H: h}} }
+	oneWay := func(x, y, w, h float64) Solid {
+		s := solid(x, y, w, h)
+		s.OneWay = true
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		r      Rect
+		dx, dy float64
+		solids []Solid
+		want   Result
+	}{
+		{
+			name: "zero move",
+			r:    player, solids: []Solid{solid(0, 48, 100, 20)},
+			want: Result{Rect: player, Ground: -1, Head: -1},
+		},
+		{
+			name: "free move",
+			r:    player, dx: 5, dy: -3,
+			want: Result{Rect: at(5, -3), Ground: -1, Head: -1},
+		},
+		{
+			name: "fall at 15px onto a 20px ledge",
+			r:    at(0, 0), dy: 15, solids: []Solid{solid(0, 55, 100, 20)},
+			want: Result{Rect: at(0, 7), Floor: true, Ground: 0, Head: -1},
+		},
+		{
+			name: "fall at 15px from just above a 20px ledge",
+			r:    at(0, 6.5), dy: 15, solids: []Solid{solid(0, 55, 100, 20)},
+			want: Result{Rect: at(0, 7), Floor: true, Ground: 0, Head: -1},
+		},
+		{
+			name: "fast fall does not tunnel through a thin ledge",
+			r:    at(0, 0), dy: 60, solids: []Solid{solid(0, 60, 100, 4)},
+			want: Result{Rect: at(0, 12), Floor: true, Ground: 0, Head: -1},
+		},
+		{
+			name: "lands on the first of two stacked ledges",
+			r:    at(0, 0), dy: 40, solids: []Solid{solid(0, 80, 100, 20), solid(0, 60, 100, 20)},
+			want: Result{Rect: at(0, 12), Floor: true, Ground: 1, Head: -1},
+		},
+		{
+			name: "walk across the seam between two floor tiles",
+			r:    at(10, 52), dx: 8, dy: 1, solids: []Solid{solid(0, 100, 32, 32), solid(32, 100, 32, 32)},
+			want: Result{Rect: at(18, 52), Floor: true, Ground: 0, Head: -1},
+		},
+		{
+			name: "walk left across the seam",
+			r:    at(40, 52), dx: -8, dy: 1, solids: []Solid{solid(0, 100, 32, 32), solid(32, 100, 32, 32)},
+			want: Result{Rect: at(32, 52), Floor: true, Ground: 1, Head: -1},
+		},
+		{
+			name: "walk into a wall",
+			r:    at(0, 52), dx: 10, solids: []Solid{solid(36, 0, 20, 200)},
+			want: Result{Rect: at(4, 52), Right: true, Ground: -1, Head: -1},
+		},
+		{
+			name: "walk left into a wall",
+			r:    at(30, 52), dx: -10, solids: []Solid{solid(0, 0, 24, 200)},
+			want: Result{Rect: at(24, 52), Left: true, Ground: -1, Head: -1},
+		},
+		{
+			name: "jump into a ceiling",
+			r:    at(0, 30), dy: -12, solids: []Solid{solid(0, 0, 100, 20)},
+			want: Result{Rect: at(0, 20), Ceiling: true, Ground: -1, Head: 0},
+		},
+		{
+			name: "one-way platform from below",
+			r:    at(0, 30), dy: -12, solids: []Solid{oneWay(0, 0, 100, 20)},
+			want: Result{Rect: at(0, 18), Ground: -1, Head: -1},
+		},
+		{
+			name: "one-way platform from above",
+			r:    at(0, 0), dy: 15, solids: []Solid{oneWay(0, 55, 100, 20)},
+			want: Result{Rect: at(0, 7), Floor: true, Ground: 0, Head: -1},
+		},
+		{
+			name: "one-way platform from the side",
+			r:    at(0, 40), dx: 10, solids: []Solid{oneWay(36, 60, 100, 20)},
+			want: Result{Rect: at(10, 40), Ground: -1, Head: -1},
+		},
+		{
+			name: "rising out of a one-way platform does not land on it",
+			r:    at(0, 20), dy: -5, solids: []Solid{oneWay(0, 60, 100, 20)},
+			want: Result{Rect: at(0, 15), Ground: -1, Head: -1},
+		},
+		{
+			name: "start inside a solid and move out",
+			r:    at(10, 10), dx: 5, dy: 5, solids: []Solid{solid(0, 0, 100, 100)},
+			want: Result{Rect: at(15, 15), Ground: -1, Head: -1},
+		},
+		{
+			name: "start inside a solid and still stop at the next one",
+			r:    at(10, 10), dy: 20, solids: []Solid{solid(0, 0, 100, 30), solid(0, 70, 100, 20)},
+			want: Result{Rect: at(10, 22), Floor: true, Ground: 1, Head: -1},
+		},
+		{
+			name: "diagonal jump stops at the wall and keeps rising",
+			r:    at(0, 100), dx: 16, dy: -16, solids: []Solid{solid(40, 0, 20, 200)},
+			want: Result{Rect: at(8, 84), Right: true, Ground: -1, Head: -1},
+		},
+		{
+			name: "standing exactly beside a wall is not a hit when moving away",
+			r:    at(0, 52), dx: -4, solids: []Solid{solid(32, 0, 20, 200)},
+			want: Result{Rect: at(-4, 52), Ground: -1, Head: -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Move(tt.r, tt.dx, tt.dy, tt.solids); got != tt.want {
+				t.Errorf("Move(%+v, %v, %v) = %+v, want %+v", tt.r, tt.dx, tt.dy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwept(t *testing.T) {
+	r := Rect{X: 10, Y: 20, W: 4, H: 6}
+	tests := []struct {
+		dx, dy float64
+		want   Rect
+	}{
+		{0, 0, r},
+		{5, 3, Rect{X: 10, Y: 20, W: 9, H: 9}},
+		{-5, -3, Rect{X: 5, Y: 17, W: 9, H: 9}},
+	}
+	for _, tt := range tests {
+		if got := r.Swept(tt.dx, tt.dy); got != tt.want {
+			t.Errorf("Swept(%v, %v) = %+v, want %+v", tt.dx, tt.dy, got, tt.want)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	r := Rect{X: 0, Y: 0, W: 10, H: 10}
+	tests := []struct {
+		o    Rect
+		want bool
+	}{
+		{Rect{X: 5, Y: 5, W: 10, H: 10}, true},
+		{Rect{X: 10, Y: 0, W: 10, H: 10}, false}, // 辺が接しているだけ
+		{Rect{X: 0, Y: 10, W: 10, H: 10}, false},
+		{Rect{X: 2, Y: 2, W: 2, H: 2}, true},
+		{Rect{X: 20, Y: 20, W: 1, H: 1}, false},
+	}
+	for _, tt := range tests {
+		if got := r.Overlaps(tt.o); got != tt.want {
+			t.Errorf("Overlaps(%+v) = %v, want %v", tt.o, got, tt.want)
+		}
+	}
+}
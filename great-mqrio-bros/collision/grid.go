@@ -0,0 +1,107 @@
+package collision
+
+import (
+	"math"
+	"slices"
+)
+
+// Grid は矩形を一定サイズのセルに登録しておき、ある範囲と重なりうるものだけを
+// 取り出すための空間インデックス。ステージが横に長くなっても、問い合わせの手間は
+// 問い合わせる範囲の広さと、そこにあるものの数だけで決まる。
+//
+// 登録するものは 0 から始まる添字（id）で区別する。呼び出し側のスライスの添字をそのまま使う想定。
+type Grid struct {
+	cellSize float64
+	cells    map[cell][]int
+	spans    []span   // id ごとの登録中のセル範囲
+	marks    []uint32 // Query で同じ id を 2 回返さないための印
+	mark     uint32
+}
+
+type cell struct{ x, y int }
+
+// span はセルの範囲 [x0, x1] × [y0, y1]。ok が false なら未登録。
+type span struct {
+	x0, y0, x1, y1 int
+	ok             bool
+}
+
+// NewGrid は cellSize 四方のセルで区切ったグリッドを作る。
+// セルは登録するものの典型的な大きさの数倍くらいにすると効率がよい。
+func NewGrid(cellSize float64) *Grid {
+	return &Grid{cellSize: cellSize, cells: map[cell][]int{}}
+}
+
+func (g *Grid) spanOf(r Rect) span {
+	return span{
+		x0: int(math.Floor(r.X / g.cellSize)),
+		y0: int(math.Floor(r.Y / g.cellSize)),
+		x1: int(math.Floor(r.Right() / g.cellSize)),
+		y1: int(math.Floor(r.Bottom() / g.cellSize)),
+		ok: true,
+	}
+}
+
+// Update は id を r の位置に登録する。登録済みなら前の位置から移す。
+// セルの範囲が変わらなければ何もしないので、動くものを毎フレーム呼んでも軽い。
+func (g *Grid) Update(id int, r Rect) {
+	if id >= len(g.spans) {
+		g.spans = append(g.spans, make([]span, id+1-len(g.spans))...)
+		g.marks = append(g.marks, make([]uint32, id+1-len(g.marks))...)
+	}
+	next := g.spanOf(r)
+	prev := g.spans[id]
+	if prev == next {
+		return
+	}
+	if prev.ok {
+		for y := prev.y0; y <= prev.y1; y++ {
+			for x := prev.x0; x <= prev.x1; x++ {
+				c := cell{x, y}
+				ids := g.cells[c]
+				if i := slices.Index(ids, id); i >= 0 {
+					ids = slices.Delete(ids, i, i+1)
+				}
+				if len(ids) == 0 {
+					delete(g.cells, c)
+				} else {
+					g.cells[c] = ids
+				}
+			}
+		}
+	}
+	for y := next.y0; y <= next.y1; y++ {
+		for x := next.x0; x <= next.x1; x++ {
+			c := cell{x, y}
+			g.cells[c] = append(g.cells[c], id)
+		}
+	}
+	g.spans[id] = next
+}
+
+// Query は r と同じセルに登録されている id を dst に追加して返す（小さい順、重複なし）。
+// 返すのは候補なので、実際に重なっているかは呼び出し側で確かめること。
+func (g *Grid) Query(r Rect, dst []int) []int {
+	g.mark++
+	if g.mark == 0 {
+		// 一周したら印を消してやり直す
+		clear(g.marks)
+		g.mark = 1
+	}
+	start := len(dst)
+	s := g.spanOf(r)
+	for y := s.y0; y <= s.y1; y++ {
+		for x := s.x0; x <= s.x1; x++ {
+			for _, id := range g.cells[cell{x, y}] {
+				if g.marks[id] == g.mark {
+					continue
+				}
+				g.marks[id] = g.mark
+				dst = append(dst, id)
+			}
+		}
+	}
+	// 登録順に処理したときと結果が変わらないよう、添字の順に並べる
+	slices.Sort(dst[start:])
+	return dst
+}
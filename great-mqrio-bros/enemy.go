@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+)
+
+const (
+	stompScore         = 100
+	enemyJumpPower     = -9  // jumper のジャンプの強さ
+	enemyJumpInterval  = 90  // jumper が着地してから次に跳ぶまでの最短のフレーム数
+	enemyJumpJitter    = 60  // jumper の跳ぶ間隔に足すランダムなフレーム数の最大
+	flyerAmplitude     = 40  // flyer が上下に揺れる幅
+	flyerPeriod        = 120 // flyer が 1 往復するフレーム数
+	shellSpeed         = 6   // 蹴った甲羅の速さ
+	shellKickGraceTime = 12  // 蹴った直後、プレイヤーに当たってもダメージにならないフレーム数
+)
+
+// EnemyKind は敵の種類
+type EnemyKind int
+
+const (
+	EnemyWalker EnemyKind = iota // 歩いて、壁や足場の端で折り返す
+	EnemyJumper                  // walker と同じように歩き、ときどき跳ねる
+	EnemyFlyer                   // leftBound〜rightBound を往復しながら上下に揺れて飛ぶ（重力なし）
+	EnemyShell                   // 踏むと甲羅になり、蹴ると滑って他の敵を倒す
+)
+
+// parseEnemyKind はレベルファイルの "kind" を敵の種類に変換する（省略時は walker）
+func parseEnemyKind(s string) (EnemyKind, error) {
+	switch s {
+	case "", "walker":
+		return EnemyWalker, nil
+	case "jumper":
+		return EnemyJumper, nil
+	case "flyer":
+		return EnemyFlyer, nil
+	case "shell":
+		return EnemyShell, nil
+	}
+	return 0, fmt.Errorf("unknown enemy kind %q (want walker, jumper, flyer or shell)", s)
+}
+
+// EnemyBehavior は敵の種類ごとのふるまい
+type EnemyBehavior interface {
+	// update は敵を 1 フレーム分動かす
+	update(g *Game, e *Enemy)
+	// stomped はプレイヤーに上から踏まれたときの処理
+	stomped(g *Game, e *Enemy)
+	// touched はプレイヤーに横から触れたときの処理。プレイヤーがダメージを受けるなら true を返す。
+	touched(g *Game, e *Enemy) bool
+}
+
+// enemyBehaviors は種類ごとのふるまい（状態は Enemy 側に持たせるので共有してよい）
+var enemyBehaviors = map[EnemyKind]EnemyBehavior{
+	EnemyWalker: walkerBehavior{},
+	EnemyJumper: jumperBehavior{},
+	EnemyFlyer:  flyerBehavior{},
+	EnemyShell:  shellBehavior{},
+}
+
+// reset は敵を最初の位置・状態に戻す
+func (e *Enemy) reset() {
+	e.isAlive = true
+	e.x = e.initialX
+	e.y = e.initialY
+	e.vx = e.initialVx
+	e.vy = 0
+	e.isGrounded = false
+	e.timer = 0
+	e.isShell = false
+}
+
+// defeatEnemy は敵を倒してスコアを加算する
+func (g *Game) defeatEnemy(e *Enemy, score int) {
+	e.isAlive = false
+	g.score += score
+	g.checkExtraLife()
+	g.playSound(SoundEnemy)
+}
+
+// walkEnemy は重力をかけて歩かせ、壁にぶつかるか（turnAtLedge なら）足場の端に来たら折り返す
+func (g *Game) walkEnemy(e *Enemy, turnAtLedge bool) {
+	hitWall, landed := g.moveBody(&e.x, &e.y, &e.vx, &e.vy, e.width, e.height)
+	e.isGrounded = landed
+	if hitWall || (turnAtLedge && landed && !g.groundAhead(e)) {
+		e.vx = -e.vx
+	}
+	if e.y > screenHeight || g.touchingHazard(e.rect(), true) {
+		e.isAlive = false // 穴や溶岩に落ちた
+	}
+}
+
+// groundAhead は敵の進行方向の足元に足場があるか
+func (g *Game) groundAhead(e *Enemy) bool {
+	probe := collision.Rect{X: e.x + e.width, Y: e.y + e.height, W: 1, H: 2}
+	if e.vx < 0 {
+		probe.X = e.x - 1
+	}
+	for _, s := range g.platformsNear(probe) {
+		if s.Overlaps(probe) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkerBehavior は踏むと倒せる普通の敵
+type walkerBehavior struct{}
+
+func (walkerBehavior) update(g *Game, e *Enemy) { g.walkEnemy(e, true) }
+
+func (walkerBehavior) stomped(g *Game, e *Enemy) { g.defeatEnemy(e, stompScore) }
+
+func (walkerBehavior) touched(g *Game, e *Enemy) bool { return true }
+
+// jumperBehavior は歩きながらときどき跳ねる敵。
+// 跳ぶ間隔は g.rng で揺らす（シードはリプレイに記録するので、再生でも同じタイミングで跳ぶ）。
+type jumperBehavior struct{ walkerBehavior }
+
+func (jumperBehavior) update(g *Game, e *Enemy) {
+	if e.isGrounded {
+		e.timer++
+		if e.timer >= enemyJumpInterval {
+			e.timer = -g.rng.IntN(enemyJumpJitter + 1) // 次は enemyJumpInterval〜+enemyJumpJitter フレーム後
+			e.vy = enemyJumpPower
+		}
+	}
+	g.walkEnemy(e, true)
+}
+
+// flyerBehavior は重力を受けずに飛ぶ敵
+type flyerBehavior struct{ walkerBehavior }
+
+func (flyerBehavior) update(g *Game, e *Enemy) {
+	e.timer++
+	e.x += e.vx
+	if e.x <= e.leftBound {
+		e.x = e.leftBound
+		e.vx = math.Abs(e.vx)
+	}
+	if e.x >= e.rightBound {
+		e.x = e.rightBound
+		e.vx = -math.Abs(e.vx)
+	}
+	e.y = e.initialY + flyerAmplitude*math.Sin(2*math.Pi*float64(e.timer)/flyerPeriod)
+}
+
+// shellBehavior は甲羅の敵。踏むと止まった甲羅になり、もう一度触れると蹴り出せる。
+// 滑っている甲羅は当たった敵を倒し、プレイヤーにもダメージを与える。
+type shellBehavior struct{}
+
+func (shellBehavior) update(g *Game, e *Enemy) {
+	if !e.isShell {
+		g.walkEnemy(e, true)
+		return
+	}
+	if e.vx == 0 {
+		g.walkEnemy(e, false) // 止まった甲羅も足場が崩れたら落ちる
+		return
+	}
+
+	e.timer++
+	g.walkEnemy(e, false)
+	g.nearby = g.enemyGrid.Query(e.rect(), g.nearby[:0])
+	for _, j := range g.nearby {
+		other := &g.enemies[j]
+		if other == e || !other.isAlive || !other.rect().Overlaps(e.rect()) {
+			continue
+		}
+		g.defeatEnemy(other, stompScore)
+	}
+}
+
+func (shellBehavior) stomped(g *Game, e *Enemy) {
+	switch {
+	case !e.isShell:
+		e.isShell = true
+		e.vx = 0
+		g.score += stompScore
+		g.checkExtraLife()
+		g.playSound(SoundEnemy)
+	case e.vx == 0:
+		g.kickShell(e)
+	default:
+		e.vx = 0 // 滑っている甲羅を踏むと止まる
+	}
+}
+
+func (shellBehavior) touched(g *Game, e *Enemy) bool {
+	if !e.isShell {
+		return true
+	}
+	if e.vx == 0 {
+		g.kickShell(e)
+		return false
+	}
+	return e.timer > shellKickGraceTime
+}
+
+// kickShell は止まっている甲羅をプレイヤーと反対の方向へ蹴り出す
+func (g *Game) kickShell(e *Enemy) {
+	e.timer = 0
+	e.vx = shellSpeed
+	if g.player.x+g.player.width/2 > e.x+e.width/2 {
+		e.vx = -shellSpeed
+	}
+}
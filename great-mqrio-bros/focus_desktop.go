@@ -0,0 +1,11 @@
+//go:build !js
+
+package main
+
+// setupFocus はデスクトップ版では何もしない（ウィンドウのフォーカスが外れても止めない）
+func setupFocus() {}
+
+// lostFocus はデスクトップ版では常に false
+func lostFocus() bool {
+	return false
+}
@@ -0,0 +1,33 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// blurred はブラウザのタブからフォーカスが外れたか（lostFocus で読むと戻る）
+var blurred atomic.Bool
+
+// setupFocus はタブのフォーカスが外れたり隠れたりしたのを見張る
+func setupFocus() {
+	js.Global().Call("addEventListener", "blur", js.FuncOf(func(js.Value, []js.Value) any {
+		blurred.Store(true)
+		return nil
+	}))
+	doc := js.Global().Get("document")
+	doc.Call("addEventListener", "visibilitychange", js.FuncOf(func(js.Value, []js.Value) any {
+		if doc.Get("hidden").Bool() {
+			blurred.Store(true)
+		}
+		return nil
+	}))
+}
+
+// lostFocus は前に呼んでからタブのフォーカスが外れたか（今も外れているか）
+func lostFocus() bool {
+	return blurred.Swap(false) || !ebiten.IsFocused()
+}
@@ -0,0 +1,406 @@
+//go:build ignore
+
+// gen_sprites.go はスプライトアトラス（assets/sprites.png と assets/sprites.json）を生成する。
+// ドット絵を直接描く代わりに矩形の組み合わせで描いているので、色や形はここを直して作り直す。
+//
+//	go generate    # または go run gen_sprites.go
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+)
+
+type rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type animFrame struct {
+	Frame    string `json:"frame"`
+	Duration int    `json:"duration"`
+}
+
+type atlas struct {
+	Image      string                 `json:"image"`
+	Frames     map[string]rect        `json:"frames"`
+	Animations map[string][]animFrame `json:"animations"`
+}
+
+var (
+	img = image.NewRGBA(image.Rect(0, 0, 256, 288))
+	out = atlas{
+		Image:      "sprites.png",
+		Frames:     map[string]rect{},
+		Animations: map[string][]animFrame{},
+	}
+)
+
+// fill は (ox+x, oy+y) から w×h を塗る
+func fill(ox, oy, x, y, w, h int, c color.RGBA) {
+	for py := oy + y; py < oy+y+h; py++ {
+		for px := ox + x; px < ox+x+w; px++ {
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+// frame は (x, y) の w×h をフレーム name として登録する
+func frame(name string, x, y, w, h int) {
+	out.Frames[name] = rect{X: x, Y: y, W: w, H: h}
+}
+
+// anim は各フレーム duration フレームずつのアニメーションを登録する
+func anim(name string, duration int, frames ...string) {
+	for _, f := range frames {
+		out.Animations[name] = append(out.Animations[name], animFrame{Frame: f, Duration: duration})
+	}
+}
+
+// palette はプレイヤーのパワーアップ段階ごとの色
+type palette struct {
+	cap, shirt, overalls color.RGBA
+}
+
+var (
+	skin    = color.RGBA{R: 255, G: 220, B: 177, A: 255}
+	black   = color.RGBA{A: 255}
+	hair    = color.RGBA{R: 90, G: 50, B: 20, A: 255}
+	shoe    = color.RGBA{R: 110, G: 60, B: 20, A: 255}
+	button  = color.RGBA{R: 255, G: 215, B: 0, A: 255}
+	red     = color.RGBA{R: 220, G: 30, B: 30, A: 255}
+	blue    = color.RGBA{R: 40, G: 70, B: 200, A: 255}
+	white   = color.RGBA{R: 250, G: 245, B: 240, A: 255}
+	pose    = []string{"idle", "walk-0", "walk-1", "jump", "skid"}
+	players = []struct {
+		name   string
+		height int
+		pal    palette
+	}{
+		{"small", 48, palette{cap: red, shirt: red, overalls: blue}},
+		{"big", 72, palette{cap: red, shirt: red, overalls: blue}},
+		{"fire", 72, palette{cap: white, shirt: white, overalls: red}},
+	}
+)
+
+// drawPlayer は右向きのプレイヤーを描く。48px の高さで設計し、h に合わせて縦に伸ばす。
+func drawPlayer(ox, oy, h int, pal palette, pose string) {
+	sy := func(v int) int { return v * h / 48 }
+	part := func(x, y, w, hh int, c color.RGBA) {
+		fill(ox, oy, x, sy(y), w, sy(y+hh)-sy(y), c)
+	}
+
+	// 帽子とつば
+	part(6, 0, 18, 6, pal.cap)
+	part(6, 6, 24, 3, pal.cap)
+	// 顔・目・ひげ・後ろ髪
+	part(8, 9, 18, 13, skin)
+	part(6, 10, 4, 8, hair)
+	part(20, 11, 3, 5, black)
+	part(16, 18, 12, 2, hair)
+	// 体（腕を上げるかどうか）
+	part(6, 22, 20, 10, pal.shirt)
+	switch pose {
+	case "jump":
+		part(24, 12, 6, 10, pal.shirt)
+		part(24, 9, 6, 4, skin)
+	case "skid":
+		// 進んでいた向き（後ろ）へ手を伸ばして踏ん張る
+		part(2, 20, 6, 6, pal.shirt)
+		part(0, 18, 4, 4, skin)
+	default:
+		part(26, 24, 4, 6, skin)
+	}
+	// オーバーオールとボタン
+	part(10, 24, 12, 14, pal.overalls)
+	part(11, 26, 3, 3, button)
+	part(18, 26, 3, 3, button)
+
+	// 足
+	switch pose {
+	case "idle":
+		part(8, 38, 6, 6, pal.overalls)
+		part(18, 38, 6, 6, pal.overalls)
+		part(6, 44, 9, 4, shoe)
+		part(18, 44, 10, 4, shoe)
+	case "walk-0":
+		part(4, 37, 6, 7, pal.overalls)
+		part(22, 37, 6, 7, pal.overalls)
+		part(1, 44, 9, 4, shoe)
+		part(22, 44, 10, 4, shoe)
+	case "walk-1":
+		part(12, 38, 8, 6, pal.overalls)
+		part(11, 44, 12, 4, shoe)
+	case "skid":
+		part(4, 37, 6, 7, pal.overalls)
+		part(20, 38, 7, 6, pal.overalls)
+		part(2, 44, 9, 4, shoe)
+		part(21, 42, 11, 6, shoe)
+	case "jump":
+		part(4, 36, 6, 6, pal.overalls)
+		part(20, 38, 6, 6, pal.overalls)
+		part(0, 40, 8, 4, shoe)
+		part(20, 44, 11, 4, shoe)
+	}
+}
+
+// drawEnemy は 24×24 の敵（キノコ型）を描く。step で足を入れ替える。
+func drawEnemy(ox, oy int, step bool) {
+	body := color.RGBA{R: 139, G: 90, B: 43, A: 255}
+	face := color.RGBA{R: 240, G: 200, B: 160, A: 255}
+	fill(ox, oy, 4, 0, 16, 4, body)
+	fill(ox, oy, 1, 4, 22, 10, body)
+	fill(ox, oy, 6, 6, 4, 5, white)
+	fill(ox, oy, 14, 6, 4, 5, white)
+	fill(ox, oy, 7, 8, 2, 3, black)
+	fill(ox, oy, 15, 8, 2, 3, black)
+	fill(ox, oy, 6, 14, 12, 5, face)
+	if step {
+		fill(ox, oy, 1, 19, 9, 5, black)
+		fill(ox, oy, 15, 20, 8, 4, black)
+	} else {
+		fill(ox, oy, 1, 20, 8, 4, black)
+		fill(ox, oy, 14, 19, 9, 5, black)
+	}
+}
+
+// drawKoopa は 24×24 の甲羅の敵を描く。shell なら甲羅だけ。
+func drawKoopa(ox, oy int, step, shell bool) {
+	green := color.RGBA{R: 40, G: 170, B: 60, A: 255}
+	rim := color.RGBA{R: 250, G: 240, B: 200, A: 255}
+	head := color.RGBA{R: 250, G: 210, B: 80, A: 255}
+	if shell {
+		fill(ox, oy, 3, 8, 18, 12, green)
+		fill(ox, oy, 1, 18, 22, 3, rim)
+		fill(ox, oy, 8, 10, 8, 6, color.RGBA{R: 20, G: 120, B: 40, A: 255})
+		return
+	}
+	fill(ox, oy, 15, 0, 8, 9, head)
+	fill(ox, oy, 19, 2, 2, 3, black)
+	fill(ox, oy, 1, 7, 17, 11, green)
+	fill(ox, oy, 0, 16, 19, 3, rim)
+	if step {
+		fill(ox, oy, 3, 19, 5, 5, head)
+		fill(ox, oy, 13, 19, 5, 5, head)
+	} else {
+		fill(ox, oy, 6, 19, 5, 5, head)
+		fill(ox, oy, 10, 19, 5, 5, head)
+	}
+}
+
+// drawFlyer は 24×24 の羽のある敵を描く。up なら羽を上げる。
+func drawFlyer(ox, oy int, up bool) {
+	body := color.RGBA{R: 200, G: 60, B: 60, A: 255}
+	fill(ox, oy, 6, 6, 12, 14, body)
+	fill(ox, oy, 8, 9, 3, 4, white)
+	fill(ox, oy, 13, 9, 3, 4, white)
+	fill(ox, oy, 9, 10, 2, 3, black)
+	fill(ox, oy, 14, 10, 2, 3, black)
+	fill(ox, oy, 8, 20, 8, 3, black)
+	if up {
+		fill(ox, oy, 0, 2, 6, 8, white)
+		fill(ox, oy, 18, 2, 6, 8, white)
+	} else {
+		fill(ox, oy, 0, 12, 6, 6, white)
+		fill(ox, oy, 18, 12, 6, 6, white)
+	}
+}
+
+// drawQuestion は 24×24 のハテナブロックを描く。body は本体の色。
+func drawQuestion(ox, oy int, body color.RGBA) {
+	edge := color.RGBA{R: 150, G: 80, B: 20, A: 255}
+	fill(ox, oy, 0, 0, 24, 24, edge)
+	fill(ox, oy, 1, 1, 22, 22, body)
+	for _, p := range [][2]int{{3, 3}, {19, 3}, {3, 19}, {19, 19}} {
+		fill(ox, oy, p[0], p[1], 2, 2, edge)
+	}
+	// 「?」
+	fill(ox, oy, 8, 5, 8, 2, edge)
+	fill(ox, oy, 14, 7, 2, 4, edge)
+	fill(ox, oy, 11, 11, 4, 2, edge)
+	fill(ox, oy, 11, 13, 2, 2, edge)
+	fill(ox, oy, 11, 17, 2, 2, edge)
+}
+
+// drawCoin は 24×24 のコインを描く。width は回転して見える幅。
+func drawCoin(ox, oy, width int) {
+	gold := color.RGBA{R: 255, G: 215, B: 0, A: 255}
+	shine := color.RGBA{R: 255, G: 245, B: 160, A: 255}
+	edge := color.RGBA{R: 200, G: 140, B: 0, A: 255}
+	x := (24 - width) / 2
+	fill(ox, oy, x, 2, width, 20, edge)
+	if width > 4 {
+		fill(ox, oy, x+1, 1, width-2, 22, gold)
+		fill(ox, oy, x+2, 4, 2, 12, shine)
+	}
+}
+
+func main() {
+	// プレイヤー: 段階ごとに 1 行、ポーズごとに 1 列
+	y := 0
+	for _, p := range players {
+		for i, ps := range pose {
+			drawPlayer(i*32, y, p.height, p.pal, ps)
+			frame("player-"+p.name+"-"+ps, i*32, y, 32, p.height)
+		}
+		prefix := "player-" + p.name
+		anim(prefix+"-idle", 1, prefix+"-idle")
+		anim(prefix+"-walk", 8, prefix+"-walk-0", prefix+"-walk-1")
+		anim(prefix+"-jump", 1, prefix+"-jump")
+		anim(prefix+"-skid", 1, prefix+"-skid")
+		y += p.height
+	}
+
+	// 敵・コイン（y = 192 の行）
+	drawEnemy(0, 192, false)
+	drawEnemy(24, 192, true)
+	frame("enemy-walk-0", 0, 192, 24, 24)
+	frame("enemy-walk-1", 24, 192, 24, 24)
+	anim("enemy-walk", 12, "enemy-walk-0", "enemy-walk-1")
+	for i, w := range []int{20, 14, 4, 14} {
+		drawCoin(48+i*24, 192, w)
+		frame("coin-"+string(rune('0'+i)), 48+i*24, 192, 24, 24)
+	}
+	anim("coin-spin", 8, "coin-0", "coin-1", "coin-2", "coin-3")
+
+	// 足場のブロック（白黒で描き、足場の色を掛けて使う）
+	light := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	mid := color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	dark := color.RGBA{R: 140, G: 140, B: 140, A: 255}
+	fill(144, 192, 0, 0, 24, 24, mid)
+	fill(144, 192, 0, 0, 24, 2, light)
+	fill(144, 192, 0, 0, 2, 24, light)
+	fill(144, 192, 0, 22, 24, 2, dark)
+	fill(144, 192, 22, 0, 2, 24, dark)
+	fill(144, 192, 11, 2, 2, 10, dark)
+	fill(144, 192, 2, 11, 20, 2, dark)
+	frame("block", 144, 192, 24, 24)
+
+	// すり抜け足場: 上の板と細い支柱だけ
+	fill(0, 216, 0, 0, 24, 6, light)
+	fill(0, 216, 0, 5, 24, 1, dark)
+	fill(0, 216, 3, 6, 3, 18, mid)
+	fill(0, 216, 18, 6, 3, 18, mid)
+	frame("oneway", 0, 216, 24, 24)
+
+	// 崩れる足場: ひびの入ったブロック
+	fill(24, 216, 0, 0, 24, 24, mid)
+	fill(24, 216, 0, 0, 24, 2, light)
+	fill(24, 216, 0, 22, 24, 2, dark)
+	for i := 0; i < 10; i++ {
+		fill(24, 216, 4+i, 3+2*i, 2, 2, dark)
+		fill(24, 216, 19-i/2, 4+2*i, 1, 2, dark)
+	}
+	frame("crumble", 24, 216, 24, 24)
+
+	// 甲羅の敵・羽の敵（y = 216 の行）
+	drawKoopa(48, 216, false, false)
+	drawKoopa(72, 216, true, false)
+	drawKoopa(96, 216, false, true)
+	frame("koopa-walk-0", 48, 216, 24, 24)
+	frame("koopa-walk-1", 72, 216, 24, 24)
+	frame("shell", 96, 216, 24, 24)
+	anim("koopa-walk", 12, "koopa-walk-0", "koopa-walk-1")
+	drawFlyer(120, 216, true)
+	drawFlyer(144, 216, false)
+	frame("flyer-0", 120, 216, 24, 24)
+	frame("flyer-1", 144, 216, 24, 24)
+	anim("flyer-fly", 8, "flyer-0", "flyer-1")
+
+	// ハテナブロック（光り方を変えた 2 コマ）・使用済みブロック・レンガ。色は掛けずにそのまま使う。
+	drawQuestion(168, 216, color.RGBA{R: 240, G: 180, B: 40, A: 255})
+	drawQuestion(192, 216, color.RGBA{R: 255, G: 210, B: 90, A: 255})
+	frame("question-0", 168, 216, 24, 24)
+	frame("question-1", 192, 216, 24, 24)
+	anim("question-shine", 16, "question-0", "question-0", "question-1")
+	used := color.RGBA{R: 140, G: 100, B: 60, A: 255}
+	fill(216, 216, 0, 0, 24, 24, used)
+	fill(216, 216, 0, 0, 24, 1, black)
+	fill(216, 216, 0, 23, 24, 1, black)
+	fill(216, 216, 0, 0, 1, 24, black)
+	fill(216, 216, 23, 0, 1, 24, black)
+	for _, p := range [][2]int{{3, 3}, {19, 3}, {3, 19}, {19, 19}} {
+		fill(216, 216, p[0], p[1], 2, 2, black)
+	}
+	frame("used", 216, 216, 24, 24)
+	fill(212, 192, 0, 0, 24, 24, color.RGBA{R: 180, G: 80, B: 40, A: 255})
+	mortar := color.RGBA{R: 60, G: 30, B: 20, A: 255}
+	for row := 0; row < 4; row++ {
+		fill(212, 192, 0, row*6+5, 24, 1, mortar)
+		// 段ごとに目地をずらす
+		for x := (row % 2) * 6; x < 24; x += 12 {
+			fill(212, 192, x, row*6, 1, 5, mortar)
+		}
+	}
+	frame("brick", 212, 192, 24, 24)
+
+	// トゲ・溶岩（y = 240 の行）
+	steel := color.RGBA{R: 170, G: 170, B: 180, A: 255}
+	for row := 0; row < 24; row++ {
+		// 幅 12 の三角形を 2 つ並べる
+		w := row/2 + 1
+		fill(0, 240, 6-w/2, row, w, 1, steel)
+		fill(0, 240, 18-w/2, row, w, 1, steel)
+	}
+	frame("spikes", 0, 240, 24, 24)
+	lava := color.RGBA{R: 230, G: 70, B: 20, A: 255}
+	glow := color.RGBA{R: 255, G: 180, B: 40, A: 255}
+	for i := 0; i < 2; i++ {
+		ox := 24 + i*24
+		fill(ox, 240, 0, 0, 24, 24, lava)
+		// 表面の波を半周期ずらして流れて見せる
+		for x := 0; x < 24; x++ {
+			h := 3
+			if (x/6+i)%2 == 0 {
+				h = 5
+			}
+			fill(ox, 240, x, 0, 1, h, glow)
+		}
+		fill(ox, 240, 4+i*8, 12, 4, 2, glow)
+		fill(ox, 240, 14-i*6, 18, 3, 2, glow)
+		frame("lava-"+string(rune('0'+i)), ox, 240, 24, 24)
+	}
+	anim("lava-flow", 20, "lava-0", "lava-1")
+
+	// ゴール: ポール（縦に並べる）・先端の玉・旗
+	pole := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+	fill(168, 192, 0, 0, 8, 24, pole)
+	fill(168, 192, 2, 0, 2, 24, color.RGBA{R: 160, G: 160, B: 160, A: 255})
+	frame("goal-pole", 168, 192, 8, 24)
+	ball := color.RGBA{R: 50, G: 180, B: 50, A: 255}
+	fill(176, 192, 2, 0, 8, 12, ball)
+	fill(176, 192, 0, 2, 12, 8, ball)
+	frame("goal-top", 176, 192, 12, 12)
+	flag := color.RGBA{R: 255, G: 50, B: 50, A: 255}
+	for row := 0; row < 16; row++ {
+		// 右へ行くほど細くなる三角旗
+		w := 24 - 3*max(row-8, 7-row)
+		fill(188, 192, 0, row, w, 1, flag)
+	}
+	frame("goal-flag", 188, 192, 24, 16)
+
+	f, err := os.Create("assets/sprites.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("assets/sprites.json", append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
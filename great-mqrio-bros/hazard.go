@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+)
+
+// 水中では gravity・jumpPower・moveSpeed の代わりにこれらを使う
+const (
+	swimGravity = 0.15
+	swimStroke  = -4.5 // ジャンプボタンを押すたびに水をかいて上がる速さ
+	swimSpeed   = 2.5
+	swimMaxFall = 3 // 水中で沈む速さの上限
+)
+
+// HazardKind は触れるとやられる場所の種類
+type HazardKind int
+
+const (
+	HazardSpikes HazardKind = iota // トゲ。プレイヤーだけがやられる
+	HazardLava                     // 溶岩。プレイヤーも敵もやられる
+	HazardPit                      // 穴。画面下まで落ちるのを待たずにやられる（敵も）
+)
+
+// parseHazardKind はレベルファイルの "kind" を危険地帯の種類に変換する
+func parseHazardKind(s string) (HazardKind, error) {
+	switch s {
+	case "spikes":
+		return HazardSpikes, nil
+	case "lava":
+		return HazardLava, nil
+	case "pit":
+		return HazardPit, nil
+	}
+	return 0, fmt.Errorf("unknown hazard kind %q (want spikes, lava or pit)", s)
+}
+
+// Hazard は触れるとやられる場所
+type Hazard struct {
+	x, y, width, height float64
+	kind                HazardKind
+}
+
+// Water は水中の物理になる範囲
+type Water struct {
+	x, y, width, height float64
+}
+
+func (h *Hazard) rect() collision.Rect {
+	return collision.Rect{X: h.x, Y: h.y, W: h.width, H: h.height}
+}
+
+// touchingHazard は r が危険地帯に重なっているか。forEnemy なら敵に効かないトゲは無視する。
+func (g *Game) touchingHazard(r collision.Rect, forEnemy bool) bool {
+	g.nearby = g.hazardGrid.Query(r, g.nearby[:0])
+	for _, i := range g.nearby {
+		h := &g.hazards[i]
+		if forEnemy && h.kind == HazardSpikes {
+			continue
+		}
+		if h.rect().Overlaps(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// inWater は r の中心が水の中にあるか（水面から頭だけ出ているときは水中ではない）
+func (g *Game) inWater(r collision.Rect) bool {
+	cx, cy := r.X+r.W/2, r.Y+r.H/2
+	// 水は数が少なく横に広いので、空間インデックスを使わずに全部見る
+	for _, w := range g.waters {
+		if cx >= w.x && cx < w.x+w.width && cy >= w.y && cy < w.y+w.height {
+			return true
+		}
+	}
+	return false
+}
+
+// drawHazards はトゲと溶岩を描く（穴は見えない）
+func (g *Game) drawHazards(screen *ebiten.Image, cam float64) {
+	useSprites := g.sprites != nil && !g.debugDraw
+	for _, i := range g.visible(g.hazardGrid) {
+		h := &g.hazards[i]
+		switch h.kind {
+		case HazardSpikes:
+			if useSprites {
+				drawTiled(screen, g.sprites.frame("spikes"), h.x-cam, h.y, h.width, h.height, nil)
+				continue
+			}
+			// 幅 16 ごとに、下ほど広い段で三角形のトゲを描く
+			for x := h.x; x < h.x+h.width; x += 16 {
+				for step := 0.0; step < 4; step++ {
+					w := min(4+step*4, h.x+h.width-x)
+					vector.DrawFilledRect(screen, float32(x+8-w/2-cam), float32(h.y+step*h.height/4), float32(w), float32(h.height/4),
+						color.RGBA{R: 160, G: 160, B: 170, A: 255}, false)
+				}
+			}
+		case HazardLava:
+			if useSprites {
+				drawTiled(screen, g.sprites.animFrame("lava-flow", g.elapsedFrames), h.x-cam, h.y, h.width, h.height, nil)
+				continue
+			}
+			vector.DrawFilledRect(screen, float32(h.x-cam), float32(h.y), float32(h.width), float32(h.height),
+				color.RGBA{R: 230, G: 70, B: 20, A: 255}, false)
+			vector.DrawFilledRect(screen, float32(h.x-cam), float32(h.y), float32(h.width), 4,
+				color.RGBA{R: 255, G: 180, B: 40, A: 255}, false)
+		}
+	}
+}
+
+// drawWater は水を半透明で重ねて描く（プレイヤーや敵の後に描く）
+func (g *Game) drawWater(screen *ebiten.Image, cam float64) {
+	for _, w := range g.waters {
+		if w.x+w.width < cam || w.x > cam+screenWidth {
+			continue
+		}
+		vector.DrawFilledRect(screen, float32(w.x-cam), float32(w.y), float32(w.width), float32(w.height),
+			color.NRGBA{R: 20, G: 70, B: 160, A: 110}, false)
+		vector.DrawFilledRect(screen, float32(w.x-cam), float32(w.y), float32(w.width), 2,
+			color.NRGBA{R: 170, G: 210, B: 255, A: 200}, false)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+// Input は 1 フレーム分の入力状態
+type Input struct {
+	Left   bool
+	Right  bool
+	Jump   bool // ジャンプ・決定
+	Pause  bool // 一時停止
+	Action bool // ダッシュ・ファイアボール
+}
+
+// 実際のキー・ボタンは Bindings で割り当てる（bindings.go の deviceInput）。
+
+// InputSource は Update に毎フレームの入力を供給する。
+// Update は 1 フレームにつき必ず 1 回だけ Next を呼ぶので、
+// 入力列を渡せばシミュレーションをフレーム単位で再現できる。
+type InputSource interface {
+	Next() Input
+}
+
+// scriptedInput はあらかじめ決めた入力列を先頭から順に返す（テスト・ツール用）。
+// 入力列を使い切った後は何も押していない状態を返す。
+type scriptedInput struct {
+	frames []Input
+	pos    int
+}
+
+func newScriptedInput(frames ...Input) *scriptedInput {
+	return &scriptedInput{frames: frames}
+}
+
+func (s *scriptedInput) Next() Input {
+	if s.pos >= len(s.frames) {
+		return Input{}
+	}
+	in := s.frames[s.pos]
+	s.pos++
+	return in
+}
+
+// hold は同じ入力を n フレーム分並べる。newScriptedInput と組み合わせて使う。
+//
+//	newScriptedInput(append(hold(Input{Right: true}, 60), hold(Input{Jump: true}, 1)...)...)
+func hold(in Input, n int) []Input {
+	frames := make([]Input, n)
+	for i := range frames {
+		frames[i] = in
+	}
+	return frames
+}
@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// levelFormatVersion は現在サポートしているレベルファイル・ステージ一覧のバージョン
+const levelFormatVersion = 1
+
+// stageListPath はプレイ順のステージ一覧ファイル
+const stageListPath = "levels/stages.json"
+
+// levelFS はレベルファイル一式（デスクトップ・WASM 共通でバイナリに埋め込む）
+//
+//go:embed levels/*.json
+var levelFS embed.FS
+
+// defaultPlatformColor は color 省略時の足場の色（茶色）
+var defaultPlatformColor = color.RGBA{R: 139, G: 69, B: 19, A: 255}
+
+// Level はレベルファイル（JSON）の内容
+type Level struct {
+	Version     int             `json:"version"`
+	Name        string          `json:"name"`
+	Width       float64         `json:"width"`
+	Spawn       LevelPoint      `json:"spawn"`
+	Platforms   []LevelPlatform `json:"platforms"`
+	Enemies     []LevelEnemy    `json:"enemies"`
+	Coins       []LevelCoin     `json:"coins"`
+	Checkpoints []LevelPoint    `json:"checkpoints,omitempty"` // 中間地点（ポールの根元の位置）
+	Items       []LevelItem     `json:"items,omitempty"`
+	Hazards     []LevelHazard   `json:"hazards,omitempty"`   // トゲ・溶岩・穴
+	Water       []LevelArea     `json:"water,omitempty"`     // 泳ぎの物理になる範囲
+	Music       string          `json:"music,omitempty"`     // BGM（overworld / underground / castle。省略時は overworld）
+	TimeLimit   int             `json:"timeLimit,omitempty"` // 制限時間（秒）。0 なら制限なし
+	Goal        *LevelGoal      `json:"goal"`
+}
+
+// LevelPoint はレベル内の座標
+type LevelPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// LevelPlatform は足場のエントリ
+type LevelPlatform struct {
+	X        float64      `json:"x"`
+	Y        float64      `json:"y"`
+	Width    float64      `json:"width"`
+	Height   float64      `json:"height"`
+	Color    string       `json:"color,omitempty"`    // "#rrggbb"。省略時は defaultPlatformColor
+	Kind     string       `json:"kind,omitempty"`     // "solid"（省略時）/ "oneway" / "crumble" / "question" / "brick"
+	Path     []LevelPoint `json:"path,omitempty"`     // 動く足場が回る点（左上）。出発点 → path[0] → … → 出発点 の順
+	Speed    float64      `json:"speed,omitempty"`    // 動く速さ（px/フレーム）。省略時は defaultPlatformSpeed
+	Contents string       `json:"contents,omitempty"` // question のみ: "coin"（省略時）/ "mushroom" / "flower"
+	Surface  string       `json:"surface,omitempty"`  // "normal"（省略時）/ "ice"
+}
+
+// LevelEnemy は敵のエントリ
+type LevelEnemy struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+	VX         float64 `json:"vx"`
+	Kind       string  `json:"kind,omitempty"`       // "walker"（省略時）/ "jumper" / "flyer" / "shell"
+	LeftBound  float64 `json:"leftBound,omitempty"`  // flyer が往復する範囲の左端
+	RightBound float64 `json:"rightBound,omitempty"` // flyer が往復する範囲の右端
+}
+
+// LevelCoin はコインのエントリ
+type LevelCoin struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
+// LevelHazard は触れるとやられる場所のエントリ
+type LevelHazard struct {
+	Kind string `json:"kind"` // "spikes" / "lava" / "pit"
+	LevelArea
+}
+
+// LevelArea は左上の位置と大きさで表す範囲
+type LevelArea struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// validate は範囲の大きさが正で、横方向が幅 stageWidth のステージに収まっているかを調べる
+func (a LevelArea) validate(stageWidth float64) error {
+	if a.Width <= 0 || a.Height <= 0 {
+		return fmt.Errorf("size must be positive (got %vx%v)", a.Width, a.Height)
+	}
+	if a.X < 0 || a.X+a.Width > stageWidth {
+		return fmt.Errorf("x range %v-%v is outside the stage", a.X, a.X+a.Width)
+	}
+	return nil
+}
+
+// LevelItem はパワーアップアイテムのエントリ
+type LevelItem struct {
+	Kind string  `json:"kind"` // "mushroom" / "flower"
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// LevelGoal はゴール（旗）のエントリ
+type LevelGoal struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	PoleHeight float64 `json:"poleHeight"`
+}
+
+// Stage はステージ一覧の 1 エントリ（ワールド・ステージ番号と読み込み済みのレベル）
+type Stage struct {
+	World  int
+	Number int
+	Level  *Level
+}
+
+// stageList はステージ一覧ファイル（JSON）の内容
+type stageList struct {
+	Version int `json:"version"`
+	Stages  []struct {
+		World int    `json:"world"`
+		Stage int    `json:"stage"`
+		File  string `json:"file"` // 一覧ファイルからの相対パス
+	} `json:"stages"`
+}
+
+// LevelError はレベルファイルのどのエントリ（何行目）が不正かを示すエラー
+type LevelError struct {
+	File  string // レベルファイルのパス
+	Line  int    // 問題のある行（1 から。0 なら不明）
+	Entry string // 問題のあるエントリ（例: "enemies[3].leftBound"。JSON の文法エラーなら空）
+	Err   error
+}
+
+func (e *LevelError) Error() string {
+	loc := e.File
+	if e.Line > 0 {
+		loc += ":" + strconv.Itoa(e.Line)
+	}
+	if e.Entry == "" {
+		return fmt.Sprintf("%s: %v", loc, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", loc, e.Entry, e.Err)
+}
+
+func (e *LevelError) Unwrap() error {
+	return e.Err
+}
+
+// loadLevel は fsys からレベルファイルを読み込み、検証して返す
+func loadLevel(fsys fs.FS, path string) (*Level, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	return parseLevel(path, data)
+}
+
+// loadStages はステージ一覧と、そこに並ぶ全レベルを読み込む。
+// 起動時にすべて検証しておくことで、後半のステージの誤りもすぐに分かる。
+func loadStages(fsys fs.FS, listPath string) ([]Stage, error) {
+	data, err := fs.ReadFile(fsys, listPath)
+	if err != nil {
+		return nil, err
+	}
+	var list stageList
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&list); err != nil {
+		return nil, &LevelError{File: listPath, Err: err}
+	}
+	if list.Version != levelFormatVersion {
+		return nil, &LevelError{File: listPath, Entry: "version", Err: fmt.Errorf("unsupported version %d (want %d)", list.Version, levelFormatVersion)}
+	}
+	if len(list.Stages) == 0 {
+		return nil, &LevelError{File: listPath, Entry: "stages", Err: errors.New("no stages")}
+	}
+
+	stages := make([]Stage, 0, len(list.Stages))
+	for i, s := range list.Stages {
+		if s.World <= 0 || s.Stage <= 0 {
+			return nil, &LevelError{File: listPath, Entry: fmt.Sprintf("stages[%d]", i), Err: fmt.Errorf("world/stage must be positive (got %d-%d)", s.World, s.Stage)}
+		}
+		l, err := loadLevel(fsys, path.Join(path.Dir(listPath), s.File))
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, Stage{World: s.World, Number: s.Stage, Level: l})
+	}
+	return stages, nil
+}
+
+// parseLevel は JSON をデコードして検証する。path はエラーメッセージ用。
+func parseLevel(path string, data []byte) (*Level, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // タイプミスしたキーを黙って無視しない
+
+	var l Level
+	if err := dec.Decode(&l); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, &LevelError{File: path, Line: lineAt(data, syntaxErr.Offset), Err: err}
+		case errors.As(err, &typeErr):
+			return nil, &LevelError{File: path, Line: lineAt(data, typeErr.Offset), Entry: typeErr.Field, Err: err}
+		}
+		// DisallowUnknownFields のエラーには場所が入っていないので、型にないキーを探して示す
+		if entry, offset, ok := findEntry(data, reflect.TypeFor[Level](), func(_ string, known bool) bool { return !known }); ok {
+			return nil, &LevelError{File: path, Line: lineAt(data, offset), Entry: entry, Err: errors.New("unknown field")}
+		}
+		return nil, &LevelError{File: path, Err: err}
+	}
+	if err := l.validate(); err != nil {
+		var le *LevelError
+		if errors.As(err, &le) {
+			le.File = path
+			le.Line = entryLine(data, le.Entry)
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// lineAt はバイトオフセットを行番号（1 から）に変換する
+func lineAt(data []byte, offset int64) int {
+	offset = min(max(offset, 0), int64(len(data)))
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// entryLine は validate が返したエントリ（"platforms[1].kind" など）の行を返す。
+// 省略したキー（kind を書いていない足場など）なら、そのキーを持つエントリの行にする。
+func entryLine(data []byte, entry string) int {
+	for entry != "" {
+		if _, offset, ok := findEntry(data, reflect.TypeFor[Level](), func(e string, _ bool) bool { return e == entry }); ok {
+			return lineAt(data, offset)
+		}
+		i := strings.LastIndexAny(entry, ".[")
+		if i < 0 {
+			break
+		}
+		entry = entry[:i]
+	}
+	return 0
+}
+
+// findEntry は data を t の形の JSON として先頭から読み、match が true を返した最初のエントリと、
+// その位置（キーまたは配列の要素の先頭）を返す。known はキーが t のフィールドにあるか。
+func findEntry(data []byte, t reflect.Type, match func(entry string, known bool) bool) (string, int64, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var found string
+	offset := int64(-1)
+	walkJSON(dec, data, "", t, func(entry string, known bool, at int64) bool {
+		if match(entry, known) {
+			found, offset = entry, at
+			return true
+		}
+		return false
+	})
+	return found, offset, offset >= 0
+}
+
+// walkJSON は entry の値を 1 つ読み、中のキー・配列の要素ごとに visit を呼ぶ。
+// visit が true を返したか、途中で読めなくなったら true を返してやめる。
+func walkJSON(dec *json.Decoder, data []byte, entry string, t reflect.Type, visit func(entry string, known bool, at int64) bool) (stop bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return true
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			at := skipSeparators(data, dec.InputOffset())
+			tok, err := dec.Token()
+			if err != nil {
+				return true
+			}
+			key, _ := tok.(string)
+			child := key
+			if entry != "" {
+				child = entry + "." + key
+			}
+			ft, known := fieldType(t, key)
+			if visit(child, known, at) || walkJSON(dec, data, child, ft, visit) {
+				return true
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return true
+		}
+	case json.Delim('['):
+		var et reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			et = t.Elem()
+		}
+		for i := 0; dec.More(); i++ {
+			at := skipSeparators(data, dec.InputOffset())
+			child := fmt.Sprintf("%s[%d]", entry, i)
+			if visit(child, true, at) || walkJSON(dec, data, child, et, visit) {
+				return true
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSeparators は offset から空白と , : を読み飛ばした位置を返す（Decoder は区切りを次の Token で読むため）
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// fieldType は構造体 t の JSON のキー key のフィールドの型を返す（encoding/json と同じく大文字小文字は区別しない）。
+// t が構造体でなければ（型が分からなければ）どのキーもあることにする。
+func fieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, true
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// validate はレベルの内容が遊べる状態か検証する
+func (l *Level) validate() error {
+	invalid := func(entry, format string, args ...any) error {
+		return &LevelError{Entry: entry, Err: fmt.Errorf(format, args...)}
+	}
+
+	if l.Version != levelFormatVersion {
+		return invalid("version", "unsupported version %d (want %d)", l.Version, levelFormatVersion)
+	}
+	if l.Width < screenWidth {
+		return invalid("width", "must be at least %d (got %v)", screenWidth, l.Width)
+	}
+	if l.Spawn.X < 0 || l.Spawn.X > l.Width-playerWidth {
+		return invalid("spawn.x", "%v is outside the stage", l.Spawn.X)
+	}
+	if l.Spawn.Y+playerHeight > screenHeight {
+		return invalid("spawn.y", "%v is below the screen", l.Spawn.Y)
+	}
+
+	for i, p := range l.Platforms {
+		entry := fmt.Sprintf("platforms[%d]", i)
+		if p.Width <= 0 || p.Height <= 0 {
+			return invalid(entry, "size must be positive (got %vx%v)", p.Width, p.Height)
+		}
+		if p.X < 0 || p.X+p.Width > l.Width {
+			return invalid(entry, "x range %v-%v is outside the stage", p.X, p.X+p.Width)
+		}
+		if p.Color != "" {
+			if _, err := parseHexColor(p.Color); err != nil {
+				return invalid(entry+".color", "%v", err)
+			}
+		}
+		kind, err := parsePlatformKind(p.Kind)
+		if err != nil {
+			return invalid(entry+".kind", "%v", err)
+		}
+		if p.Contents != "" && kind != PlatformQuestion {
+			return invalid(entry+".contents", "only question blocks have contents")
+		}
+		if _, err := parseBlockContents(p.Contents); err != nil {
+			return invalid(entry+".contents", "%v", err)
+		}
+		if _, err := parseSurface(p.Surface); err != nil {
+			return invalid(entry+".surface", "%v", err)
+		}
+		if p.Speed < 0 {
+			return invalid(entry+".speed", "must not be negative (got %v)", p.Speed)
+		}
+		for j, pt := range p.Path {
+			if pt.X < 0 || pt.X+p.Width > l.Width {
+				return invalid(fmt.Sprintf("%s.path[%d].x", entry, j), "x range %v-%v is outside the stage", pt.X, pt.X+p.Width)
+			}
+		}
+	}
+
+	for i, e := range l.Enemies {
+		entry := fmt.Sprintf("enemies[%d]", i)
+		if e.Width <= 0 || e.Height <= 0 {
+			return invalid(entry, "size must be positive (got %vx%v)", e.Width, e.Height)
+		}
+		kind, err := parseEnemyKind(e.Kind)
+		if err != nil {
+			return invalid(entry+".kind", "%v", err)
+		}
+		if e.X < 0 || e.X+e.Width > l.Width {
+			return invalid(entry+".x", "%v is outside the stage", e.X)
+		}
+		if kind != EnemyFlyer {
+			continue
+		}
+		if e.LeftBound > e.RightBound {
+			return invalid(entry+".leftBound", "%v is greater than rightBound %v", e.LeftBound, e.RightBound)
+		}
+		if e.X < e.LeftBound || e.X > e.RightBound {
+			return invalid(entry+".x", "%v is outside bounds %v-%v", e.X, e.LeftBound, e.RightBound)
+		}
+	}
+
+	for i, c := range l.Coins {
+		entry := fmt.Sprintf("coins[%d]", i)
+		if c.Radius <= 0 {
+			return invalid(entry+".radius", "must be positive (got %v)", c.Radius)
+		}
+		if c.X < 0 || c.X > l.Width {
+			return invalid(entry+".x", "%v is outside the stage", c.X)
+		}
+	}
+
+	for i, cp := range l.Checkpoints {
+		entry := fmt.Sprintf("checkpoints[%d]", i)
+		if cp.X < 0 || cp.X > l.Width-playerWidth {
+			return invalid(entry+".x", "%v is outside the stage", cp.X)
+		}
+		if cp.Y-playerHeight < 0 || cp.Y > screenHeight {
+			return invalid(entry+".y", "%v is outside the screen", cp.Y)
+		}
+	}
+
+	for i, it := range l.Items {
+		entry := fmt.Sprintf("items[%d]", i)
+		if _, err := parseItemKind(it.Kind); err != nil {
+			return invalid(entry+".kind", "%v", err)
+		}
+		if it.X < 0 || it.X+itemSize > l.Width {
+			return invalid(entry+".x", "%v is outside the stage", it.X)
+		}
+	}
+
+	for i, h := range l.Hazards {
+		entry := fmt.Sprintf("hazards[%d]", i)
+		if _, err := parseHazardKind(h.Kind); err != nil {
+			return invalid(entry+".kind", "%v", err)
+		}
+		if err := h.validate(l.Width); err != nil {
+			return invalid(entry, "%v", err)
+		}
+	}
+	for i, w := range l.Water {
+		if err := w.validate(l.Width); err != nil {
+			return invalid(fmt.Sprintf("water[%d]", i), "%v", err)
+		}
+	}
+
+	if _, err := parseMusic(l.Music); err != nil {
+		return invalid("music", "%v", err)
+	}
+	if l.TimeLimit < 0 {
+		return invalid("timeLimit", "must not be negative (got %d)", l.TimeLimit)
+	}
+
+	if l.Goal == nil {
+		return invalid("goal", "missing")
+	}
+	if l.Goal.X < 0 || l.Goal.X > l.Width {
+		return invalid("goal.x", "%v is outside the stage", l.Goal.X)
+	}
+	if l.Goal.PoleHeight <= 0 {
+		return invalid("goal.poleHeight", "must be positive (got %v)", l.Goal.PoleHeight)
+	}
+	return nil
+}
+
+// parseHexColor は "#rrggbb" 形式の色を変換する
+func parseHexColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("color %q must be in #rrggbb form", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("color %q must be in #rrggbb form", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// applyLevel はレベルの内容でステージ（足場・敵・コイン・ゴール）を組み立てる
+func (g *Game) applyLevel(l *Level) {
+	g.stageWidth = l.Width
+	g.spawnX = l.Spawn.X
+	g.spawnY = l.Spawn.Y
+
+	g.platforms = make([]Platform, 0, len(l.Platforms))
+	for _, p := range l.Platforms {
+		c := defaultPlatformColor
+		if p.Color != "" {
+			c, _ = parseHexColor(p.Color) // validate 済み
+		}
+		kind, _ := parsePlatformKind(p.Kind)
+		contents, _ := parseBlockContents(p.Contents)
+		surface, _ := parseSurface(p.Surface)
+		speed := p.Speed
+		if speed == 0 {
+			speed = defaultPlatformSpeed
+		}
+		g.platforms = append(g.platforms, Platform{
+			x: p.X, y: p.Y, width: p.Width, height: p.Height, color: c,
+			kind: kind, path: p.Path, speed: speed, contents: contents, surface: surface,
+			initialX: p.X, initialY: p.Y,
+		})
+	}
+
+	g.enemies = make([]Enemy, 0, len(l.Enemies))
+	for _, e := range l.Enemies {
+		kind, _ := parseEnemyKind(e.Kind) // validate 済み
+		g.enemies = append(g.enemies, Enemy{
+			x: e.X, y: e.Y, width: e.Width, height: e.Height,
+			vx: e.VX, leftBound: e.LeftBound, rightBound: e.RightBound,
+			kind: kind, behavior: enemyBehaviors[kind],
+			isAlive:  true,
+			initialX: e.X, initialY: e.Y, initialVx: e.VX,
+		})
+	}
+
+	g.coins = make([]Coin, 0, len(l.Coins))
+	for _, c := range l.Coins {
+		g.coins = append(g.coins, Coin{x: c.X, y: c.Y, radius: c.Radius})
+	}
+
+	g.checkpoints = make([]Checkpoint, 0, len(l.Checkpoints))
+	for _, cp := range l.Checkpoints {
+		g.checkpoints = append(g.checkpoints, Checkpoint{x: cp.X, y: cp.Y})
+	}
+
+	g.items = make([]Item, 0, len(l.Items))
+	for _, it := range l.Items {
+		kind, _ := parseItemKind(it.Kind) // validate 済み
+		item := Item{kind: kind, initialX: it.X, initialY: it.Y}
+		item.reset()
+		g.items = append(g.items, item)
+	}
+	g.fireballs = nil
+
+	g.hazards = make([]Hazard, 0, len(l.Hazards))
+	for _, h := range l.Hazards {
+		kind, _ := parseHazardKind(h.Kind) // validate 済み
+		g.hazards = append(g.hazards, Hazard{x: h.X, y: h.Y, width: h.Width, height: h.Height, kind: kind})
+	}
+	g.waters = make([]Water, 0, len(l.Water))
+	for _, w := range l.Water {
+		g.waters = append(g.waters, Water{x: w.X, y: w.Y, width: w.Width, height: w.Height})
+	}
+
+	g.music, _ = parseMusic(l.Music) // validate 済み
+	g.timeLimit = l.TimeLimit * 60
+
+	g.goal = Goal{x: l.Goal.X, y: l.Goal.Y, poleHeight: l.Goal.PoleHeight}
+	g.buildGrids()
+}
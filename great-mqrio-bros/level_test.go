@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 不正なレベルファイルは、悪いエントリとその行を示す LevelError になる
+func TestParseLevelErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string // testLevel のこの部分を new に置き換える
+		new   string
+		entry string
+		line  int
+	}{
+		{
+			name:  "unknown field",
+			old:   `{ "x": 250, "y": 470, "width": 150, "height": 20 }`,
+			new:   `{ "x": 250, "y": 470, "width": 150, "heigth": 20 }`,
+			entry: "platforms[1].heigth", line: 8,
+		},
+		{
+			name:  "unknown top-level field",
+			old:   `"name": "test",`,
+			new:   `"name": "test", "enemy": [],`,
+			entry: "enemy", line: 3,
+		},
+		{
+			name:  "bad platform kind",
+			old:   `{ "x": 250, "y": 470, "width": 150, "height": 20 }`,
+			new:   `{ "x": 250, "y": 470, "width": 150, "height": 20, "kind": "cloud" }`,
+			entry: "platforms[1].kind", line: 8,
+		},
+		{
+			name:  "platform outside the stage",
+			old:   `{ "x": 250, "y": 470, "width": 150, "height": 20 }`,
+			new:   `{ "x": 1500, "y": 470, "width": 150, "height": 20 }`,
+			entry: "platforms[1]", line: 8,
+		},
+		{
+			name:  "coin outside the stage",
+			old:   `{ "x": 290, "y": 440, "radius": 12 }`,
+			new:   `{ "x": 2000, "y": 440, "radius": 12 }`,
+			entry: "coins[0].x", line: 12,
+		},
+		{
+			name:  "spawn below the screen",
+			old:   `"spawn": { "x": 100, "y": 500 }`,
+			new:   `"spawn": { "x": 100, "y": 900 }`,
+			entry: "spawn.y", line: 5,
+		},
+		{
+			name:  "missing key points at its entry",
+			old:   `"goal": { "x": 1400, "y": 350, "poleHeight": 200 }`,
+			new:   `"goal": { "x": 1400, "y": 350 }`,
+			entry: "goal.poleHeight", line: 14,
+		},
+		{
+			name:  "wrong type",
+			old:   `"width": 1600,`,
+			new:   `"width": "wide",`,
+			entry: "width", line: 4,
+		},
+		{
+			name: "syntax error",
+			old:  `{ "x": 290, "y": 440, "radius": 12 }`,
+			new:  `{ "x": 290, "y": 440 "radius": 12 }`,
+			line: 12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(testLevel, tt.old, tt.new, 1)
+			if data == testLevel {
+				t.Fatalf("%q is not in testLevel", tt.old)
+			}
+			_, err := parseLevel("test.json", []byte(data))
+			var le *LevelError
+			if !errors.As(err, &le) {
+				t.Fatalf("parseLevel error = %v, want *LevelError", err)
+			}
+			if le.File != "test.json" || le.Entry != tt.entry || le.Line != tt.line {
+				t.Errorf("error at %s:%d %q, want test.json:%d %q (%v)", le.File, le.Line, le.Entry, tt.line, tt.entry, err)
+			}
+		})
+	}
+}
+
+func TestLevelErrorMessage(t *testing.T) {
+	err := &LevelError{File: "levels/1-1.json", Line: 42, Entry: "enemies[2].x", Err: errors.New("5 is outside bounds 10-20")}
+	if got, want := err.Error(), "levels/1-1.json:42: enemies[2].x: 5 is outside bounds 10-20"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// 同梱のステージはすべて読み込める
+func TestLoadStages(t *testing.T) {
+	stages, err := loadStages(levelFS, stageListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stages) == 0 {
+		t.Error("no stages")
+	}
+}
@@ -1,24 +1,43 @@
 package main
 
 import (
-	"encoding/binary"
+	"flag"
 	"fmt"
 	"image/color"
 	"log"
 	"math"
+	"math/rand/v2"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/synth"
+)
+
+// gameVersion はリプレイファイルに記録するゲームのバージョン。
+// 同じ入力で動きが変わる変更（物理・敵・当たり判定・ステージの進み方など）では必ず上げる
+// （違うバージョンで記録したリプレイを読み込むと、ずれるかもしれないと警告が出る）。
+const gameVersion = "0.12.0"
+
+const (
+	noticeFrames     = 120  // 画面上部のお知らせ（リプレイ保存など）を表示するフレーム数
+	stageIntroFrames = 120  // ステージ紹介画面を表示するフレーム数
+	clearInputDelay  = 60   // クリア画面でスペースを受け付けるまでのフレーム数（ゴール直後に押したジャンプで飛ばさないように）
+	deathFrames      = 120  // やられアニメーションのフレーム数
+	deathPauseFrames = 30   // やられた直後に止まっているフレーム数（その後跳ねて落ちる）
+	initialLives     = 3    // ゲーム開始時・コンティニュー時の残機
+	extraLifeScore   = 1000 // このスコアごとに残機+1
 )
 
 const (
 	screenWidth     = 800
 	screenHeight    = 600
-	stageWidth      = 2400
-	playerWidth     = 32
-	playerHeight    = 48
+	playerWidth     = 32 // プレイヤーの幅
+	playerHeight    = 48 // 小さい状態のプレイヤーの高さ
 	gravity         = 0.5
 	jumpPower       = -12
 	moveSpeed       = 4
@@ -29,25 +48,54 @@ const (
 type Player struct {
 	x, y          float64 // 位置
 	vx, vy        float64 // 速度（velocity）
-	isGrounded    bool    // 地面に接しているか
-	isFacingRight bool    // 右向きか
-	animFrame     int     // アニメーションフレーム番号
-	animCounter   int     // フレームカウンター
-	state         string  // "idle", "walking", "jumping"
+	width, height float64 // 当たり判定のサイズ（パワーアップで変わる）
+	power         PowerLevel
+	pendingPower  PowerLevel // 頭の上が塞がっていてまだ大きくなれていないパワーアップ（PowerSmall ならなし）
+	invincible    int        // 残りの無敵フレーム数（ダメージ直後）
+	isGrounded    bool       // 地面に接しているか
+	inWater       bool       // 水中にいるか（泳ぎの物理になる）
+	coyoteTime    int        // 地面を離れてもジャンプできる残りフレーム数
+	jumpBuffer    int        // 押したジャンプが有効な残りフレーム数（着地したらジャンプする）
+	jumpHeld      bool       // ジャンプで上昇中にボタンを押し続けている（離すと低いジャンプになる）
+	ground        int        // 乗っている足場（platforms の添字、-1 なら乗っていない）
+	isFacingRight bool       // 右向きか
+	animCounter   float64    // 今の状態になってからのフレーム数（アニメーションのコマ送りに使う。歩きは速さに合わせて進む）
+	isSkidding    bool       // 走っている向きと逆に入力してブレーキをかけている
+	state         stateMachine[PlayerState]
 }
 
 // Platform は足場の構造体
 type Platform struct {
 	x, y, width, height float64
 	color               color.RGBA
+	kind                PlatformKind
+	surface             Surface       // 表面の滑りやすさ
+	path                []LevelPoint  // 動く足場が回る点（空なら動かない）
+	speed               float64       // 動く足場の速さ（px/フレーム）
+	target              int           // 次に向かう path の添字（len(path) なら出発点へ戻る）
+	dx, dy              float64       // このフレームに動いた量（乗っているプレイヤーを運ぶ）
+	crumbleTime         int           // 崩れる足場に乗られてからのフレーム数（0 ならまだ乗られていない）
+	falling             bool          // 崩れて落ちている
+	contents            BlockContents // ハテナブロックの中身
+	used                bool          // ハテナブロックの中身を出した（使用済みブロック）
+	broken              bool          // レンガが壊れた
+	bumpTime            int           // 下から叩かれて跳ねている残りフレーム数
+	vy                  float64       // 落ちる速さ
+	initialX            float64       // リセット用
+	initialY            float64
 }
 
 // Enemy は敵キャラクターの構造体
 type Enemy struct {
 	x, y, width, height float64
-	vx                  float64 // 左右移動速度
-	leftBound           float64 // 移動範囲の左端
-	rightBound          float64 // 移動範囲の右端
+	vx, vy              float64 // 速度
+	kind                EnemyKind
+	behavior            EnemyBehavior // 種類ごとの動き（enemyBehaviors から選ぶ）
+	leftBound           float64       // 移動範囲の左端（flyer のみ）
+	rightBound          float64       // 移動範囲の右端（flyer のみ）
+	isGrounded          bool
+	timer               int  // 種類ごとに使うカウンター（ジャンプの間隔・飛ぶ位相・蹴ってからの時間）
+	isShell             bool // 踏まれて甲羅になっている（shell のみ）
 	isAlive             bool
 	initialX            float64 // リセット用
 	initialY            float64
@@ -70,245 +118,343 @@ type Goal struct {
 	isReached  bool
 }
 
+// Checkpoint は中間地点（小さな旗）の構造体
+type Checkpoint struct {
+	x, y      float64 // ポールの根元の位置
+	isReached bool
+}
+
 // Game はゲームの状態を管理する構造体
 type Game struct {
 	player             Player
 	platforms          []Platform
+	solids             []collision.Solid // platformsNear のバッファ
+	platformGrid       *collision.Grid   // 足場の空間インデックス
+	coinGrid           *collision.Grid   // コインの空間インデックス
+	enemyGrid          *collision.Grid   // 敵の空間インデックス（敵が動くたびに更新する）
+	hazardGrid         *collision.Grid   // トゲ・溶岩・穴の空間インデックス
+	hazards            []Hazard
+	waters             []Water
+	nearby             []int // 空間インデックスの問い合わせ結果のバッファ
+	enemyBuf           []int // 敵を順に処理するときの問い合わせ結果（処理中に nearby が使われるので別にする）
 	enemies            []Enemy
 	coins              []Coin
 	goal               Goal
-	gameState          string // "playing", "cleared"
-	clearTime          int    // クリア後の経過フレーム数
-	elapsedFrames      int    // プレイ開始からの経過フレーム数
-	clearElapsedFrames int    // ゴール到達時点の経過フレーム（クリアタイム表示用）
+	checkpoints        []Checkpoint
+	items              []Item
+	fireballs          []Fireball
+	particles          []Particle // ブロックから飛び出したコインやレンガの破片
+	activeCheckpoint   int        // 復活地点にする中間地点（checkpoints の添字、-1 ならスタート地点）
+	checkpointCoins    []bool     // 中間地点に触れた時点で取得済みだったコイン
+	checkpointEnemies  []bool     // 中間地点に触れた時点で倒していた敵
+	checkpointItems    []bool     // 中間地点に触れた時点で取得済みだったアイテム（ブロックから出ていたものを含む）
+	checkpointBlocks   []bool     // 中間地点に触れた時点で使用済み・壊れていたブロック
+	checkpointFrames   int        // 中間地点に触れた時点の経過フレーム
+	stageWidth         float64    // ステージの横幅（レベルファイルから読み込む）
+	spawnX, spawnY     float64    // スタート地点
+	stages             []Stage    // プレイ順のステージ一覧
+	stageIndex         int        // 現在のステージ（stages の添字）
+	gameState          stateMachine[GameState]
+	scenes             []Scene // 積んでいる画面（一番上だけが進む）
+	introTime          int     // ステージ紹介画面の経過フレーム数
+	deathTime          int     // やられてからの経過フレーム数
+	gameOverTime       int     // ゲームオーバー画面の経過フレーム数
+	gameOverChoice     int     // ゲームオーバー画面の選択肢（0: CONTINUE, 1: RETRY）
+	lives              int     // 残機
+	nextExtraLife      int     // 次に残機が増えるスコア
+	clearTime          int     // クリア後の経過フレーム数
+	elapsedFrames      int     // プレイ開始からの経過フレーム数
+	clearElapsedFrames int     // ゴール到達時点の経過フレーム（クリアタイム表示用）
+	timeLimit          int     // ステージの制限時間（フレーム数。0 なら制限なし）
+	timeLeft           int     // 残り時間（フレーム数）
 	cameraX            float64
 	score              int
-	audioContext       *audio.Context
-	jumpSound          *audio.Player
-	coinSound          *audio.Player
-	enemySound         *audio.Player
-	goalSound          *audio.Player
-}
-
-// generateBeep は指定周波数・長さのサイン波を16bit LE ステレオPCMで返す
-func generateBeep(sampleRate, durationMs, frequencyHz int) []byte {
-	numSamples := sampleRate * durationMs / 1000
-	// 2チャンネル（ステレオ）、各サンプル2バイト → 1サンプルあたり4バイト
-	buf := make([]byte, numSamples*4)
-	for i := 0; i < numSamples; i++ {
-		t := float64(i) / float64(sampleRate)
-		sample := math.Sin(2 * math.Pi * float64(frequencyHz) * t)
-		sampleInt := int16(sample * 32767 * 0.3)
-		u := uint16(sampleInt)
-		// L, R 同じ値でステレオ
-		binary.LittleEndian.PutUint16(buf[i*4:], u)
-		binary.LittleEndian.PutUint16(buf[i*4+2:], u)
-	}
-	return buf
-}
-
-// NewGame は新しいゲームを作成
-func NewGame() *Game {
-	audioContext := audio.NewContext(audioSampleRate)
-	jumpPCM := generateBeep(audioSampleRate, 100, 440)
-	coinPCM := generateBeep(audioSampleRate, 150, 880)
-	enemyPCM := generateBeep(audioSampleRate, 200, 220)
-
-	goalPCM := generateBeep(audioSampleRate, 500, 660)
-
-	jumpPlayer := audioContext.NewPlayerFromBytes(jumpPCM)
-	coinPlayer := audioContext.NewPlayerFromBytes(coinPCM)
-	enemyPlayer := audioContext.NewPlayerFromBytes(enemyPCM)
-	goalPlayer := audioContext.NewPlayerFromBytes(goalPCM)
-
-	return &Game{
+	stageStartScore    int              // ステージを始めたときのスコア（ステージごとの記録用）
+	records            Records          // ステージごとの上位記録（nil なら記録しない）
+	clearRecord        ClearRecord      // 今クリアしたステージの記録の順位
+	input              InputSource      // 毎フレームの入力元（キーボード・スクリプト・リプレイ）
+	prevInput          Input            // 前のフレームの入力（押した瞬間の判定用）
+	physics            Physics          // プレイヤーの動きの調整値
+	tuning             Tuning           // F4 の調整パネル
+	bindings           *Bindings        // 操作の割り当て（deviceInput と共有する）
+	rebind             Rebind           // F2 の割り当て画面
+	touch              *TouchControls   // 画面のタッチ操作（deviceInput と共有する。ヘッドレス実行では nil）
+	liveInput          InputSource      // リプレイ再生前の入力元（再生終了後に戻す）
+	livePhysics        Physics          // リプレイ再生前の調整値（再生終了後に戻す）
+	hotkeys            bool             // F8/F9 などのホットキーを受け付けるか（ヘッドレス実行では false）
+	seed               uint64           // 乱数シード（リプレイに記録する）
+	rng                *rand.Rand       // ゲーム内の乱数は必ずこれを使う（リプレイを再現するため）
+	recording          *Replay          // 記録中のリプレイ（再生中は nil）
+	replayLoads        chan []byte      // 読み込んだリプレイファイル（ブラウザのファイル選択などから届く）
+	sprites            *SpriteAtlas     // nil ならベクター描画（デバッグ表示）のみ
+	debugDraw          bool             // スプライトの代わりにベクター描画（当たり判定そのままの四角形）で描く
+	notice             string           // 画面上部のお知らせ
+	noticeTime         int              // お知らせの残り表示フレーム数
+	audioContext       *audio.Context   // nil なら効果音なし
+	sounds             *SoundPool       // 効果音（nil なら鳴らさない）
+	mixer              Mixer            // 全体・BGM・効果音の音量
+	audioChanged       bool             // 設定画面で音量を変えた（閉じるときに保存する）
+	music              string           // 今のステージの曲（musicTracks のキー）
+	bgm                *audio.Player    // 再生中の BGM（ステージ紹介画面に入ると捨てて、次は最初から鳴らす）
+	bgmSequencer       *synth.Sequencer // bgm の音源（テンポを変えるのに使う）
+}
+
+// NewGame はキーボード・ゲームパッド操作、効果音ありの新しいゲームを作成
+func NewGame() (*Game, error) {
+	bindings, touch := defaultBindings(), &TouchControls{}
+	g, err := newGame(&deviceInput{bindings: bindings, touch: touch}, audio.NewContext(audioSampleRate))
+	if err != nil {
+		return nil, err
+	}
+	g.hotkeys = true
+	g.bindings = bindings
+	g.touch = touch
+	g.loadBindings()
+	g.loadAudioSettings()
+	g.loadRecords()
+	if g.sprites, err = loadAtlas(assetFS, spriteAtlasPath); err != nil {
+		return nil, err
+	}
+	g.gameState.reset(StateTitle)
+	g.setScene(&titleScene{g: g})
+	return g, nil
+}
+
+// newGame は入力元と音声コンテキストを指定してゲームを作成する。
+// audioContext が nil なら効果音なしで動くので、ウィンドウを開かずに
+// Update を N フレーム進めるテストやツールから使える。
+func newGame(input InputSource, audioContext *audio.Context) (*Game, error) {
+	stages, err := loadStages(levelFS, stageListPath)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Game{
 		audioContext:       audioContext,
-		jumpSound:          jumpPlayer,
-		coinSound:          coinPlayer,
-		enemySound:         enemyPlayer,
-		goalSound:          goalPlayer,
-		gameState:          "playing",
+		stages:             stages,
+		input:              input,
+		liveInput:          input,
+		player:             Player{width: playerWidth, height: playerHeight},
+		physics:            defaultPhysics(),
+		bindings:           defaultBindings(),
+		mixer:              defaultMixer(),
+		replayLoads:        make(chan []byte, 1),
 		elapsedFrames:      0,
 		clearElapsedFrames: 0,
-		goal: Goal{
-			x:          stageWidth - 25, // 右端の浮き床(〜2350)より十分右に配置
-			y:          450,
-			poleHeight: 150,
-			flagHeight: 0,
-			isReached:  false,
-		},
-		player: Player{
-			x:             100,
-			y:             100,
-			isFacingRight: true,
-		},
-		platforms: []Platform{
-			// 地面（ステージ全体）
-			{x: 0, y: 550, width: stageWidth, height: 50, color: color.RGBA{R: 100, G: 200, B: 100, A: 255}},
-			// エリア1 (0-800)
-			{x: 200, y: 450, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 400, y: 350, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 600, y: 450, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 350, y: 250, width: 100, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			// エリア2 (800-1600)
-			{x: 1000, y: 450, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 1200, y: 350, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 1400, y: 450, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 1150, y: 250, width: 100, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			// エリア3 (1600-2400)
-			{x: 1800, y: 450, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 2000, y: 350, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 2200, y: 450, width: 150, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-			{x: 1950, y: 250, width: 100, height: 20, color: color.RGBA{R: 139, G: 69, B: 19, A: 255}},
-		},
-		enemies: []Enemy{
-			{x: 250, y: 426, width: 24, height: 24, vx: 2, leftBound: 200, rightBound: 326, isAlive: true, initialX: 250, initialY: 426, initialVx: 2},
-			{x: 450, y: 326, width: 24, height: 24, vx: -2, leftBound: 400, rightBound: 526, isAlive: true, initialX: 450, initialY: 326, initialVx: -2},
-			{x: 650, y: 426, width: 24, height: 24, vx: -2, leftBound: 600, rightBound: 726, isAlive: true, initialX: 650, initialY: 426, initialVx: -2},
-			{x: 375, y: 226, width: 24, height: 24, vx: 1.5, leftBound: 350, rightBound: 426, isAlive: true, initialX: 375, initialY: 226, initialVx: 1.5},
-			{x: 1050, y: 426, width: 24, height: 24, vx: -2, leftBound: 1000, rightBound: 1126, isAlive: true, initialX: 1050, initialY: 426, initialVx: -2},
-			{x: 1250, y: 326, width: 24, height: 24, vx: 2, leftBound: 1200, rightBound: 1326, isAlive: true, initialX: 1250, initialY: 326, initialVx: 2},
-			{x: 1450, y: 426, width: 24, height: 24, vx: -2, leftBound: 1400, rightBound: 1526, isAlive: true, initialX: 1450, initialY: 426, initialVx: -2},
-			{x: 1175, y: 226, width: 24, height: 24, vx: 1.5, leftBound: 1150, rightBound: 1226, isAlive: true, initialX: 1175, initialY: 226, initialVx: 1.5},
-			{x: 1850, y: 426, width: 24, height: 24, vx: 2, leftBound: 1800, rightBound: 1926, isAlive: true, initialX: 1850, initialY: 426, initialVx: 2},
-			{x: 2050, y: 326, width: 24, height: 24, vx: -2, leftBound: 2000, rightBound: 2126, isAlive: true, initialX: 2050, initialY: 326, initialVx: -2},
-			{x: 2250, y: 426, width: 24, height: 24, vx: -2, leftBound: 2200, rightBound: 2326, isAlive: true, initialX: 2250, initialY: 426, initialVx: -2},
-			{x: 1975, y: 226, width: 24, height: 24, vx: 1.5, leftBound: 1950, rightBound: 2026, isAlive: true, initialX: 1975, initialY: 226, initialVx: 1.5},
-		},
-		coins: []Coin{
-			{x: 150, y: 500, radius: 12, collected: false},
-			{x: 280, y: 410, radius: 12, collected: false},
-			{x: 350, y: 410, radius: 12, collected: false},
-			{x: 480, y: 310, radius: 12, collected: false},
-			{x: 520, y: 310, radius: 12, collected: false},
-			{x: 680, y: 410, radius: 12, collected: false},
-			{x: 400, y: 210, radius: 12, collected: false},
-			{x: 250, y: 350, radius: 12, collected: false},
-			{x: 550, y: 350, radius: 12, collected: false},
-			{x: 400, y: 450, radius: 12, collected: false},
-			{x: 100, y: 500, radius: 12, collected: false},
-			{x: 950, y: 410, radius: 12, collected: false},
-			{x: 1100, y: 310, radius: 12, collected: false},
-			{x: 1300, y: 410, radius: 12, collected: false},
-			{x: 1180, y: 210, radius: 12, collected: false},
-			{x: 1750, y: 500, radius: 12, collected: false},
-			{x: 1900, y: 410, radius: 12, collected: false},
-			{x: 2100, y: 310, radius: 12, collected: false},
-			{x: 2300, y: 500, radius: 12, collected: false},
-		},
 	}
+	if audioContext != nil {
+		g.sounds = newSoundPool(audioContext)
+		g.applyVolume()
+	}
+	g.initStateMachines()
+	g.resetToStart()
+	return g, nil
 }
 
 // Update はゲームロジックを更新（毎フレーム呼ばれる）
 func (g *Game) Update() error {
-	if g.gameState == "cleared" {
-		g.clearTime++
-		if g.goal.flagHeight < g.goal.poleHeight-20 {
-			g.goal.flagHeight += 2
+	if g.noticeTime > 0 {
+		g.noticeTime--
+	}
+	g.updateReplay()
+	if g.hotkeys && inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.debugDraw = !g.debugDraw
+	}
+	if g.updateTuning() {
+		return nil // プロファイル名の入力中はゲームを止める
+	}
+	if g.updateRebind() {
+		return nil // 割り当て画面を開いている間はゲームを止める
+	}
+	if g.hotkeys && inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		g.toggleMute()
+	}
+	if g.hotkeys && inpututil.IsKeyJustPressed(ebiten.KeyF7) {
+		g.toggleSettings()
+	}
+	if s, ok := g.topScene().(*settingsScene); ok && s.hotkey {
+		// F7 の設定画面はリプレイの入力を進めず、記録もしない
+		in := g.liveInput.Next()
+		prev := s.prev
+		s.prev = in
+		return s.update(in, prev)
+	}
+
+	// 入力は状態にかかわらず 1 フレームに 1 回だけ読む（スクリプト・リプレイとフレームを揃えるため）
+	in := g.input.Next()
+	if g.hotkeys && lostFocus() && g.autoPausable() {
+		in.Pause = true // 押したことにして記録するので、リプレイでも同じフレームで止まる
+	}
+	if _, entering := g.topScene().(*initialsScene); !entering {
+		g.recordInput(in)
+	}
+	prev := g.prevInput
+	g.prevInput = in
+
+	return g.topScene().update(in, prev)
+}
+
+// autoPausable はタブのフォーカスが外れたときに一時停止するか（プレイ中でリプレイ再生中でないとき）
+func (g *Game) autoPausable() bool {
+	if _, replaying := g.input.(*replayInput); replaying {
+		return false
+	}
+	_, playing := g.topScene().(*playScene)
+	return playing && g.gameState.current == StatePlaying
+}
+
+// updatePlay はステージを遊んでいる間（playScene が一番上のとき）のゲームロジックを 1 フレーム進める
+func (g *Game) updatePlay(in, prev Input) error {
+	switch g.gameState.current {
+	case StateIntro:
+		g.introTime++
+		if g.introTime >= stageIntroFrames {
+			g.setState(StatePlaying)
+		}
+		return nil
+	case StateDying:
+		// 少し止まってから跳ね上がり、画面下へ落ちていく
+		g.deathTime++
+		if g.deathTime > deathPauseFrames {
+			g.player.vy += gravity
+			g.player.y += g.player.vy
 		}
-		if ebiten.IsKeyPressed(ebiten.KeySpace) {
-			g.resetToStart()
+		if g.deathTime >= deathFrames {
+			g.respawn()
 		}
 		return nil
+	case StateGameOver:
+		g.gameOverTime++
+		if in.Left {
+			g.gameOverChoice = 0
+		}
+		if in.Right {
+			g.gameOverChoice = 1
+		}
+		if g.gameOverTime >= clearInputDelay && in.Jump {
+			if g.gameOverChoice == 0 {
+				g.continueGame()
+			} else {
+				g.resetToStart()
+			}
+		}
+		return nil
+	case StateCleared, StateAllClear:
+		return nil // クリア後は resultsScene が進める
 	}
 
-	// 左右移動の入力処理
-	g.player.vx = 0
-	if ebiten.IsKeyPressed(ebiten.KeyLeft) || ebiten.IsKeyPressed(ebiten.KeyA) {
-		g.player.vx = -moveSpeed
-		g.player.isFacingRight = false
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyRight) || ebiten.IsKeyPressed(ebiten.KeyD) {
-		g.player.vx = moveSpeed
-		g.player.isFacingRight = true
-	}
-
-	// ジャンプの入力処理（地面にいる時のみ）
-	if (ebiten.IsKeyPressed(ebiten.KeySpace) || ebiten.IsKeyPressed(ebiten.KeyUp) || ebiten.IsKeyPressed(ebiten.KeyW)) && g.player.isGrounded {
-		g.player.vy = jumpPower
-		g.player.isGrounded = false
-		if g.jumpSound != nil {
-			_ = g.jumpSound.Rewind()
-			g.jumpSound.Play()
-		}
+	if in.Pause && !prev.Pause {
+		g.pauseGame()
+		return nil
+	}
+	if g.updateTimer() {
+		return nil
+	}
+
+	if g.player.invincible > 0 {
+		g.player.invincible--
+	}
+	if in.Action && !prev.Action {
+		g.shootFireball()
 	}
 
-	// 重力を適用
-	g.player.vy += gravity
+	// 左右移動の入力処理（加速・減速・ブレーキ。Action を押している間は走る）
+	g.updateWalk(in.Left, in.Right, in.Action)
+
+	// ジャンプの入力処理（押した瞬間だけ。水中では押すたびに水をかいて上がる）。
+	// 押した瞬間は inpututil ではなく前のフレームの入力と比べて判定する（リプレイで同じ結果になるように）。
+	jumped := false
+	if g.player.inWater {
+		g.player.coyoteTime, g.player.jumpBuffer = 0, 0
+		if in.Jump && !prev.Jump {
+			g.player.vy = swimStroke
+			g.player.isGrounded = false
+			jumped = true
+		}
+	} else {
+		jumped = g.updateJump(in.Jump && !prev.Jump, in.Jump)
+	}
+	if jumped {
+		g.playSound(SoundJump)
+	}
 
-	// 速度の上限を設定（落下速度制限）
-	if g.player.vy > 15 {
-		g.player.vy = 15
+	// 重力を適用し、落下速度を制限（水中では浮力で重力が弱く、ゆっくり沈む）
+	if g.player.inWater {
+		g.player.vy = min(g.player.vy+swimGravity, swimMaxFall)
+	} else {
+		g.player.vy = min(g.player.vy+g.physics.Gravity, g.physics.MaxFallSpeed)
 	}
 
 	// プレイヤーの状態とアニメーションを更新
-	if !g.player.isGrounded {
-		g.player.state = "jumping"
-	} else if g.player.vx != 0 {
-		g.player.state = "walking"
-		g.player.animCounter++
-		if g.player.animCounter >= 8 {
-			g.player.animCounter = 0
-			g.player.animFrame = 1 - g.player.animFrame
+	switch {
+	case !g.player.isGrounded && g.player.vy < 0:
+		// 踏みつけの跳ね返り中は上昇していても stomp のまま
+		if g.player.state.current != PlayerStomp {
+			g.setPlayerState(PlayerJump)
 		}
-	} else {
-		g.player.state = "idle"
-		g.player.animFrame = 0
-		g.player.animCounter = 0
+	case !g.player.isGrounded:
+		g.setPlayerState(PlayerFall)
+	case g.player.isSkidding:
+		g.setPlayerState(PlayerSkid)
+	case g.player.vx != 0:
+		g.setPlayerState(PlayerWalk)
+	default:
+		g.setPlayerState(PlayerIdle)
 	}
+	g.player.animCounter += g.playerAnimRate()
 
-	// プレイヤーの位置を更新
-	g.player.x += g.player.vx
-	g.player.y += g.player.vy
+	// 足場を動かしてから、プレイヤーを動かして足場との衝突を解決
+	g.updatePlatforms()
+	if g.checkCollisions() {
+		return nil
+	}
 
-	// 衝突判定と位置補正
-	g.checkCollisions()
+	// アイテム・ファイアボールの更新
+	g.updateBlocks()
+	g.updateItems()
+	g.updatePendingPower()
+	g.updateFireballs()
 
 	// コイン取得判定
-	playerCenterX := g.player.x + playerWidth/2
-	playerCenterY := g.player.y + playerHeight/2
-	for i := range g.coins {
+	playerCenterX := g.player.x + g.player.width/2
+	playerCenterY := g.player.y + g.player.height/2
+	g.nearby = g.coinGrid.Query(g.player.rect(), g.nearby[:0])
+	for _, i := range g.nearby {
 		if g.coins[i].collected {
 			continue
 		}
 		dx := playerCenterX - g.coins[i].x
 		dy := playerCenterY - g.coins[i].y
 		distance := math.Sqrt(dx*dx + dy*dy)
-		if distance < g.coins[i].radius+playerWidth/2 {
+		if distance < g.coins[i].radius+g.player.width/2 {
 			g.coins[i].collected = true
 			g.score += 10
-			if g.coinSound != nil {
-				_ = g.coinSound.Rewind()
-				g.coinSound.Play()
-			}
+			g.checkExtraLife()
+			g.playSound(SoundCoin)
 		}
 	}
 
-	// 敵の更新（左右移動、足場の端で折り返し）
-	for i := range g.enemies {
-		if !g.enemies[i].isAlive {
+	// 敵の更新（種類ごとの動きは EnemyBehavior）。画面から遠い敵は止めておく。
+	g.enemyBuf = g.enemyGrid.Query(g.activeArea(), g.enemyBuf[:0])
+	for _, i := range g.enemyBuf {
+		e := &g.enemies[i]
+		if !e.isAlive {
 			continue
 		}
-		g.enemies[i].x += g.enemies[i].vx
-		if g.enemies[i].x <= g.enemies[i].leftBound {
-			g.enemies[i].x = g.enemies[i].leftBound
-			g.enemies[i].vx = -g.enemies[i].vx
-		}
-		if g.enemies[i].x >= g.enemies[i].rightBound {
-			g.enemies[i].x = g.enemies[i].rightBound
-			g.enemies[i].vx = -g.enemies[i].vx
-		}
+		e.behavior.update(g, e)
+		g.enemyGrid.Update(i, e.rect())
 	}
 
 	// プレイヤーと敵の衝突判定
-	for i := range g.enemies {
+	g.enemyBuf = g.enemyGrid.Query(g.player.rect(), g.enemyBuf[:0])
+	for _, i := range g.enemyBuf {
 		if !g.enemies[i].isAlive {
 			continue
 		}
 		playerLeft := g.player.x
-		playerRight := g.player.x + playerWidth
+		playerRight := g.player.x + g.player.width
 		playerTop := g.player.y
-		playerBottom := g.player.y + playerHeight
+		playerBottom := g.player.y + g.player.height
 		enemyLeft := g.enemies[i].x
 		enemyRight := g.enemies[i].x + g.enemies[i].width
 		enemyTop := g.enemies[i].y
@@ -319,21 +465,18 @@ func (g *Game) Update() error {
 			continue
 		}
 
-		// 上から踏んだ場合: 敵を倒す、スコア+100、プレイヤーが小さくジャンプ
+		// 上から踏んだ場合: 敵ごとの処理（倒す・甲羅にするなど）、プレイヤーが小さくジャンプ
 		if playerBottom < enemyTop+g.enemies[i].height/2 && g.player.vy > 0 {
-			g.enemies[i].isAlive = false
-			g.score += 100
+			g.enemies[i].behavior.stomped(g, &g.enemies[i])
 			g.player.vy = -8 // 小さくジャンプ
-			if g.enemySound != nil {
-				_ = g.enemySound.Rewind()
-				g.enemySound.Play()
-			}
+			g.setPlayerState(PlayerStomp)
 			continue
 		}
 
-		// 横から当たった場合: ゲームリセット
-		g.resetGame()
-		break
+		// 横から当たった場合: 大きければ小さくなる、小さければやられ（止まった甲羅などはダメージなし）
+		if g.enemies[i].behavior.touched(g, &g.enemies[i]) && g.hurtPlayer() {
+			return nil
+		}
 	}
 
 	// カメラ追従: プレイヤーが画面中央より右にいたらカメラを追従
@@ -341,8 +484,8 @@ func (g *Game) Update() error {
 	if targetX < 0 {
 		targetX = 0
 	}
-	if targetX > stageWidth-screenWidth {
-		targetX = stageWidth - screenWidth
+	if targetX > g.stageWidth-screenWidth {
+		targetX = g.stageWidth - screenWidth
 	}
 	g.cameraX = targetX
 
@@ -350,18 +493,20 @@ func (g *Game) Update() error {
 	if g.player.x < 0 {
 		g.player.x = 0
 	}
-	if g.player.x > stageWidth-playerWidth {
-		g.player.x = stageWidth - playerWidth
+	if g.player.x > g.stageWidth-g.player.width {
+		g.player.x = g.stageWidth - g.player.width
 	}
 
+	// 中間地点の判定
+	g.updateCheckpoints()
+
 	// ゴール判定
 	if !g.goal.isReached &&
-		g.player.x+playerWidth >= g.goal.x &&
+		g.player.x+g.player.width >= g.goal.x &&
 		g.player.x <= g.goal.x+30 {
 		g.goal.isReached = true
 		g.clearElapsedFrames = g.elapsedFrames
-		g.gameState = "cleared"
-		g.clearTime = 0
+		g.setState(StateCleared)
 		remainingCoins := 0
 		for _, c := range g.coins {
 			if !c.collected {
@@ -369,126 +514,318 @@ func (g *Game) Update() error {
 			}
 		}
 		g.score += remainingCoins * 50
-		if g.goalSound != nil {
-			_ = g.goalSound.Rewind()
-			g.goalSound.Play()
-		}
+		g.checkExtraLife()
+		g.recordClear()
+		g.playSound(SoundGoal)
+		return nil // 同じフレームで落下のやられ判定をしたり、クリアしたフレームを数えたりしない
 	}
 
-	// 画面下に落ちたらリセット
+	// 画面下に落ちたらやられ
 	if g.player.y > screenHeight {
-		g.resetGame()
+		g.killPlayer()
+		return nil
 	}
 
 	g.elapsedFrames++
 	return nil
 }
 
-// resetGame はプレイヤーをスタート地点に戻し、敵とコインを復活させる（落下時など）
-func (g *Game) resetGame() {
-	g.resetToStart()
+// killPlayer はプレイヤーをやられ状態にする。アニメーションの後に respawn される。
+func (g *Game) killPlayer() {
+	g.setState(StateDying)
+	g.player.vx = 0
+	g.player.vy = -10 // やられ時に跳ね上がる
+	g.playSound(SoundDeath)
+}
+
+// respawn は残機を 1 減らし、残っていれば最後に触れた中間地点（なければステージの最初）からやり直す。
+// スコアはそのまま。残機がなくなればゲームオーバー。
+func (g *Game) respawn() {
+	g.lives--
+	if g.lives <= 0 {
+		g.setState(StateGameOver)
+		return
+	}
+	g.setPower(PowerSmall)
+	if g.activeCheckpoint >= 0 {
+		g.restoreCheckpoint()
+	} else {
+		g.resetStage()
+	}
+	g.setState(StateIntro)
+}
+
+// continueGame はゲームオーバーから、スコアと残機を戻して同じステージをやり直す
+func (g *Game) continueGame() {
+	g.score = 0
+	g.lives = initialLives
+	g.nextExtraLife = extraLifeScore
+	g.setPower(PowerSmall)
+	g.startStage()
+}
+
+// checkExtraLife はスコアが extraLifeScore の倍数を超えるたびに残機を増やす
+func (g *Game) checkExtraLife() {
+	for g.score >= g.nextExtraLife {
+		g.lives++
+		g.nextExtraLife += extraLifeScore
+		g.showNotice("1UP!")
+		g.playSound(SoundOneUp)
+	}
 }
 
-// resetToStart はリスタート用。音声コンテキスト・Player はそのまま使い、ゲーム状態だけ初期化する。
+// resetToStart は 1 つ目のステージからやり直す（ゲームオーバーの RETRY など）
 func (g *Game) resetToStart() {
-	g.gameState = "playing"
+	g.startGame(0)
+}
+
+// startGame は stage 番目のステージから新しくゲームを始める（タイトル・ステージ選択から）。
+// 音声コンテキストはそのまま使い、スコアも含めてゲーム全体を初期化する。
+func (g *Game) startGame(stage int) {
+	g.setScene(&playScene{g: g})
+	g.stageIndex = stage
+	g.score = 0
+	g.lives = initialLives
+	g.nextExtraLife = extraLifeScore
+	g.setPower(PowerSmall)
+	g.setSeed(rand.Uint64())
+	g.startStage()
+	g.startRecording()
+}
+
+// setSeed はゲーム内の乱数をシードで初期化する
+func (g *Game) setSeed(seed uint64) {
+	g.seed = seed
+	g.rng = rand.New(rand.NewPCG(seed, seed))
+}
+
+// showNotice は画面上部に短いお知らせを表示する
+func (g *Game) showNotice(msg string) {
+	g.notice = msg
+	g.noticeTime = noticeFrames
+}
+
+// nextStage は次のステージへ進む。最後のステージなら全クリア画面にする。
+func (g *Game) nextStage() {
+	if g.stageIndex+1 >= len(g.stages) {
+		g.setState(StateAllClear)
+		return
+	}
+	g.stageIndex++
+	g.startStage()
+}
+
+// startStage は現在の stageIndex のレベルを組み立て、ステージ紹介画面から始める
+func (g *Game) startStage() {
+	g.applyLevel(g.stages[g.stageIndex].Level)
+	g.resetStage()
+	g.stageStartScore = g.score
+	g.setState(StateIntro)
+}
+
+// resetStage は現在のステージだけを初期状態に戻す（スコア・残機はそのまま）
+func (g *Game) resetStage() {
 	g.clearTime = 0
 	g.elapsedFrames = 0
 	g.clearElapsedFrames = 0
-	g.player.x = 100
-	g.player.y = 100
-	g.player.vx = 0
-	g.player.vy = 0
-	g.player.isGrounded = false
-	g.player.state = "idle"
-	g.player.animFrame = 0
-	g.player.animCounter = 0
+	g.timeLeft = g.timeLimit
+	g.resetPlayer(g.spawnX, g.spawnY)
 	g.cameraX = 0
-	g.score = 0
+	g.resetPlatforms()
+
+	g.activeCheckpoint = -1
+	for i := range g.checkpoints {
+		g.checkpoints[i].isReached = false
+	}
 
 	g.goal.isReached = false
 	g.goal.flagHeight = 0
 
 	for i := range g.enemies {
-		g.enemies[i].isAlive = true
-		g.enemies[i].x = g.enemies[i].initialX
-		g.enemies[i].y = g.enemies[i].initialY
-		g.enemies[i].vx = g.enemies[i].initialVx
+		g.enemies[i].reset()
 	}
+	g.indexEnemies()
 	for i := range g.coins {
 		g.coins[i].collected = false
 	}
+	g.removeSpawnedItems()
+	for i := range g.items {
+		g.items[i].reset()
+	}
+	g.fireballs = g.fireballs[:0]
+	g.particles = g.particles[:0]
 }
 
-// checkCollisions は衝突判定を行う
-func (g *Game) checkCollisions() {
+// resetPlayer はプレイヤーを (x, y) に止まった状態で置く
+func (g *Game) resetPlayer(x, y float64) {
+	g.player.x = x
+	g.player.y = y
+	g.player.vx = 0
+	g.player.vy = 0
 	g.player.isGrounded = false
+	g.player.inWater = false
+	g.player.coyoteTime = 0
+	g.player.jumpBuffer = 0
+	g.player.jumpHeld = false
+	g.player.ground = -1
+	g.player.isFacingRight = true
+	g.player.invincible = 0
+	g.player.state.reset(PlayerIdle)
+	g.player.animCounter = 0
+}
 
-	playerLeft := g.player.x
-	playerRight := g.player.x + playerWidth
-	playerTop := g.player.y
-	playerBottom := g.player.y + playerHeight
-
-	for _, platform := range g.platforms {
-		platLeft := platform.x
-		platRight := platform.x + platform.width
-		platTop := platform.y
-		platBottom := platform.y + platform.height
-
-		// 重なっているか判定
-		if playerRight > platLeft && playerLeft < platRight &&
-			playerBottom > platTop && playerTop < platBottom {
-
-			// 下から衝突（頭をぶつける）
-			if g.player.vy < 0 && playerTop < platBottom && playerBottom > platBottom {
-				g.player.y = platBottom
-				g.player.vy = 0
-			}
-
-			// 上から衝突（着地）
-			if g.player.vy > 0 && playerBottom > platTop && playerTop < platTop {
-				g.player.y = platTop - playerHeight
-				g.player.vy = 0
-				g.player.isGrounded = true
-			}
+// checkCollisions はプレイヤーを速度の分だけ動かし、足場にぶつかった軸はその手前で止める。
+// 動く足場に乗っていれば、先に足場が動いた分だけ運ぶ。
+// 動いた先で水に入ったかを調べ、トゲ・溶岩・穴に触れたらやられて true を返す。
+func (g *Game) checkCollisions() bool {
+	p := &g.player
+	if p.ground >= 0 {
+		pl := &g.platforms[p.ground]
+		if pl.dx != 0 || pl.dy != 0 {
+			r := p.rect()
+			res := collision.Move(r, pl.dx, pl.dy, g.platformsNear(r.Swept(pl.dx, pl.dy)))
+			p.x, p.y = res.X, res.Y
+		}
+	}
+	// 下から上がってきた足場にめり込んだら、足場の上に押し上げる
+	// （めり込んだ状態から始まると collision.Move はその足場を無視してしまうため）
+	for _, s := range g.platformsNear(p.rect()) {
+		pl := &g.platforms[s.ID]
+		if pl.dy < 0 && s.Overlaps(p.rect()) && p.y+p.height <= pl.y-pl.dy+1e-6 {
+			p.y = pl.y - p.height
+		}
+	}
 
-			// 左から衝突
-			if g.player.vx > 0 && playerRight > platLeft && playerLeft < platLeft {
-				g.player.x = platLeft - playerWidth
-			}
+	r := p.rect()
+	solids := g.platformsNear(r.Swept(p.vx, p.vy))
+	res := collision.Move(r, p.vx, p.vy, solids)
+	p.x, p.y = res.X, res.Y
+	p.isGrounded = res.Floor
+	p.ground = -1
+	if res.Ground >= 0 {
+		p.ground = solids[res.Ground].ID
+		g.standOn(p.ground)
+	}
+	if res.Floor || res.Ceiling {
+		p.vy = 0
+	}
+	if res.Left || res.Right {
+		p.vx = 0
+	}
+	if res.Head >= 0 {
+		g.bumpBlock(solids[res.Head].ID)
+	}
 
-			// 右から衝突
-			if g.player.vx < 0 && playerLeft < platRight && playerRight > platRight {
-				g.player.x = platRight
-			}
-		}
+	p.inWater = g.inWater(p.rect())
+	if g.touchingHazard(p.rect(), false) {
+		g.killPlayer()
+		return true
 	}
+	return false
 }
 
-// Draw は画面に描画（毎フレーム呼ばれる）
+// Draw は画面に描画（毎フレーム呼ばれる）。シーンを下から順に描き、最後にホットキーの画面を重ねる。
 func (g *Game) Draw(screen *ebiten.Image) {
+	for _, s := range g.visibleScenes() {
+		s.draw(screen)
+	}
+	g.drawTouch(screen)
+	g.drawTuning(screen)
+	g.drawRebind(screen)
+}
+
+// drawPlay はステージ（紹介画面・プレイ中・ゲームオーバー画面）を描く
+func (g *Game) drawPlay(screen *ebiten.Image) {
+	stage := g.stages[g.stageIndex]
+
+	// ステージ紹介画面
+	if g.gameState.current == StateIntro {
+		screen.Fill(color.RGBA{A: 255})
+		msg := fmt.Sprintf("WORLD %d-%d\n\nLives x %d\nScore: %d", stage.World, stage.Number, g.lives, g.score)
+		ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-40, screenHeight/2-20)
+		return
+	}
+
 	// 背景（空）
 	screen.Fill(color.RGBA{R: 135, G: 206, B: 235, A: 255})
 
 	// カメラオフセットを適用して描画
-	cam := float32(g.cameraX)
+	if g.sprites != nil && !g.debugDraw {
+		g.drawSprites(screen, g.cameraX)
+		g.drawPlayerSprite(screen, g.cameraX)
+	} else {
+		cam := float32(g.cameraX)
+		g.drawVector(screen, cam)
+		g.drawPlayer(screen, cam)
+	}
+	g.drawParticles(screen, g.cameraX)
+	g.drawWater(screen, g.cameraX)
+
+	// Controls and status
+	status := fmt.Sprintf(
+		"%s\n"+
+			"Pos: (%.0f, %.0f) Vel: (%.1f, %.1f) Grounded: %v\n"+
+			"World %d-%d  Lives: %d  Score: %d",
+		g.bindings.describe(),
+		g.player.x, g.player.y, g.player.vx, g.player.vy, g.player.isGrounded,
+		stage.World, stage.Number, g.lives, g.score,
+	)
+	if g.timeLimit > 0 {
+		status += fmt.Sprintf("  Time: %d", g.timeLeftSeconds())
+	}
+	if rp, ok := g.input.(*replayInput); ok {
+		status += fmt.Sprintf("\nREPLAY %d/%d", rp.pos, len(rp.frames))
+	}
+	ebitenutil.DebugPrint(screen, status)
+	if g.noticeTime > 0 {
+		ebitenutil.DebugPrintAt(screen, g.notice, screenWidth/2-40, 8)
+	}
 
-	// 足場を描画
-	for _, platform := range g.platforms {
+	// ゲームオーバー画面
+	if g.gameState.current == StateGameOver {
+		screen.Fill(color.RGBA{A: 255})
+		choices := [2]string{"  CONTINUE", "  RETRY FROM 1-1"}
+		choices[g.gameOverChoice] = "> " + choices[g.gameOverChoice][2:]
+		msg := fmt.Sprintf(
+			"GAME OVER\n\n"+
+				"Score: %d\n\n"+
+				"%s\n%s\n\n"+
+				"%s/%s = select, %s = OK",
+			g.score, choices[0], choices[1],
+			g.bindings.firstKey(ControlLeft), g.bindings.firstKey(ControlRight), g.bindings.firstKey(ControlJump),
+		)
+		ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-90, screenHeight/2-50)
+	}
+}
+
+// drawVector は足場・コイン・ゴール・中間地点・アイテム・敵を図形で描画する（スプライトがないとき・デバッグ表示用）。
+// 図形は当たり判定の範囲そのままになっている。
+func (g *Game) drawVector(screen *ebiten.Image, cam float32) {
+	// 足場を描画（画面内のものだけ）
+	for _, i := range g.visible(g.platformGrid) {
+		platform := &g.platforms[i]
+		if platform.broken {
+			continue
+		}
+		height := platform.height
+		if platform.kind == PlatformOneWay {
+			height = min(height, 6) // すり抜け足場は上面だけ
+		}
 		vector.DrawFilledRect(
 			screen,
-			float32(platform.x)-cam,
-			float32(platform.y),
+			float32(platform.x+platform.shake())-cam,
+			float32(platform.y+platform.bumpOffset()),
 			float32(platform.width),
-			float32(platform.height),
-			platform.color,
+			float32(height),
+			platform.blockColor(),
 			false,
 		)
 	}
 
 	// コインを描画（黄色い円）
 	coinColor := color.RGBA{R: 255, G: 215, B: 0, A: 255}
-	for _, coin := range g.coins {
+	for _, i := range g.visible(g.coinGrid) {
+		coin := g.coins[i]
 		if coin.collected {
 			continue
 		}
@@ -525,12 +862,28 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		false,
 	)
 
-	// 敵を描画（茶色い四角形）
-	enemyColor := color.RGBA{R: 139, G: 90, B: 43, A: 255}
-	for _, enemy := range g.enemies {
+	g.drawHazards(screen, float64(cam))
+	g.drawCheckpoints(screen, cam)
+
+	// アイテム・ファイアボールを描画
+	g.drawItems(screen, cam)
+
+	// 敵を描画（種類ごとの色の四角形）
+	enemyColors := map[EnemyKind]color.RGBA{
+		EnemyWalker: {R: 139, G: 90, B: 43, A: 255},
+		EnemyJumper: {R: 120, G: 60, B: 160, A: 255},
+		EnemyFlyer:  {R: 200, G: 60, B: 60, A: 255},
+		EnemyShell:  {R: 40, G: 170, B: 60, A: 255},
+	}
+	for _, i := range g.visible(g.enemyGrid) {
+		enemy := g.enemies[i]
 		if !enemy.isAlive {
 			continue
 		}
+		enemyColor := enemyColors[enemy.kind]
+		if enemy.isShell {
+			enemyColor = color.RGBA{R: 20, G: 100, B: 40, A: 255}
+		}
 		vector.DrawFilledRect(
 			screen,
 			float32(enemy.x)-cam,
@@ -541,26 +894,66 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			false,
 		)
 	}
+}
+
+// drawCheckpoints は中間地点（ポール＋小さな旗。触れたら緑）を描画する
+func (g *Game) drawCheckpoints(screen *ebiten.Image, cam float32) {
+	poleColor := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+	for _, cp := range g.checkpoints {
+		vector.DrawFilledRect(
+			screen,
+			float32(cp.x)-cam,
+			float32(cp.y-checkpointPoleHeight),
+			4,
+			checkpointPoleHeight,
+			poleColor,
+			false,
+		)
+		cpFlagColor := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		if cp.isReached {
+			cpFlagColor = color.RGBA{R: 50, G: 200, B: 50, A: 255}
+		}
+		vector.DrawFilledRect(
+			screen,
+			float32(cp.x+4)-cam,
+			float32(cp.y-checkpointPoleHeight),
+			16,
+			10,
+			cpFlagColor,
+			false,
+		)
+	}
+}
+
+// drawPlayer はプレイヤー（体・顔・向き）を描画する
+func (g *Game) drawPlayer(screen *ebiten.Image, cam float32) {
+	// 無敵時間中は点滅させる
+	if g.player.invincible > 0 && g.player.invincible/4%2 == 0 {
+		return
+	}
 
 	// プレイヤーを描画（体）- 状態に応じた高さ
-	bodyHeight := playerHeight
+	bodyHeight := g.player.height
 	bodyYOffset := 0.0
-	switch g.player.state {
-	case "walking":
-		if g.player.animFrame == 1 {
+	switch g.player.state.current {
+	case PlayerWalk:
+		if int(g.player.animCounter)/8%2 == 1 {
 			bodyHeight -= 2
 			bodyYOffset = 2 // 片足を上げた表現
 		}
-	case "jumping":
+	case PlayerJump, PlayerFall, PlayerStomp:
 		bodyHeight -= 4
 		bodyYOffset = 2
 	}
 	playerColor := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	if g.player.power == PowerFire {
+		playerColor = color.RGBA{R: 255, G: 150, B: 150, A: 255}
+	}
 	vector.DrawFilledRect(
 		screen,
 		float32(g.player.x)-cam,
 		float32(g.player.y+bodyYOffset),
-		playerWidth,
+		float32(g.player.width),
 		float32(bodyHeight),
 		playerColor,
 		false,
@@ -600,41 +993,6 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			false,
 		)
 	}
-
-	// Controls and status
-	status := fmt.Sprintf(
-		"Controls: ←→ or A/D = move, SPACE or ↑ or W = jump\n"+
-			"Pos: (%.0f, %.0f) Vel: (%.1f, %.1f) Grounded: %v\n"+
-			"Score: %d",
-		g.player.x, g.player.y, g.player.vx, g.player.vy, g.player.isGrounded,
-		g.score,
-	)
-	ebitenutil.DebugPrint(screen, status)
-
-	// クリア画面
-	if g.gameState == "cleared" {
-		overlay := ebiten.NewImage(screenWidth, screenHeight)
-		overlay.Fill(color.RGBA{R: 0, G: 0, B: 0, A: 180})
-		op := &ebiten.DrawImageOptions{}
-		screen.DrawImage(overlay, op)
-		clearTimeSec := float64(g.clearElapsedFrames) / 60.0
-		remainingCoins := 0
-		for _, c := range g.coins {
-			if !c.collected {
-				remainingCoins++
-			}
-		}
-		coinBonus := remainingCoins * 50
-		msg := fmt.Sprintf(
-			"STAGE CLEAR!\n\n"+
-				"Time: %.2f sec\n"+
-				"Score: %d\n"+
-				"Coin bonus: %d\n\n"+
-				"[ RESTART ] SPACE",
-			clearTimeSec, g.score, coinBonus,
-		)
-		ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-100, screenHeight/2-60)
-	}
 }
 
 // Layout は画面サイズを返す
@@ -643,13 +1001,23 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func main() {
+	flag.Parse()
+
 	// ウィンドウの設定
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Mario-style Platformer - Ebitengine")
+	setupFocus()
 
 	// ゲームを開始
-	game := NewGame()
+	game, err := NewGame()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := setupReplay(game); err != nil {
+		log.Fatal(err)
+	}
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
+	closeReplay(game)
 }
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testLevel は床と低い足場が 1 つ、足場の上にコインが 1 枚あるだけの短いステージ（敵なし）
+const testLevel = `{
+  "version": 1,
+  "name": "test",
+  "width": 1600,
+  "spawn": { "x": 100, "y": 500 },
+  "platforms": [
+    { "x": 0, "y": 550, "width": 1600, "height": 50 },
+    { "x": 250, "y": 470, "width": 150, "height": 20 }
+  ],
+  "enemies": [],
+  "coins": [
+    { "x": 290, "y": 440, "radius": 12 }
+  ],
+  "goal": { "x": 1400, "y": 350, "poleHeight": 200 }
+}`
+
+// newTestGame は testLevel だけのゲームを、ステージ紹介画面を飛ばしてプレイ中から始める
+func newTestGame(t *testing.T, frames ...Input) *Game {
+	t.Helper()
+	return newTestGameWith(t, testLevel, frames...)
+}
+
+// newTestGameWith はレベルファイルの内容 level だけのゲームをプレイ中から始める
+func newTestGameWith(t *testing.T, level string, frames ...Input) *Game {
+	t.Helper()
+	l, err := parseLevel("test.json", []byte(level))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := newGame(newScriptedInput(frames...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.stages = []Stage{{World: 1, Number: 1, Level: l}}
+	g.resetToStart()
+	g.introTime = stageIntroFrames
+	return g
+}
+
+// run は Update を n フレーム進める
+func run(t *testing.T, g *Game, n int) {
+	t.Helper()
+	for range n {
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStandStill(t *testing.T) {
+	g := newTestGame(t)
+	run(t, g, 60)
+	if got := g.gameState.current; got != StatePlaying {
+		t.Fatalf("gameState = %v, want playing", got)
+	}
+	if g.player.x != 100 || !g.player.isGrounded {
+		t.Errorf("player at x=%v grounded=%v, want x=100 on the floor", g.player.x, g.player.isGrounded)
+	}
+	if want := 550 - g.player.height; g.player.y != want {
+		t.Errorf("player.y = %v, want %v", g.player.y, want)
+	}
+}
+
+func TestWalkRight(t *testing.T) {
+	g := newTestGame(t, hold(Input{Right: true}, 60)...)
+	run(t, g, 60)
+	if g.player.x <= 200 {
+		t.Errorf("player.x = %v after walking 60 frames, want > 200", g.player.x)
+	}
+	if !g.player.isGrounded {
+		t.Error("player left the floor while walking")
+	}
+}
+
+// 右へ歩きながらジャンプして足場に乗り、足場の上のコインを取る
+func TestJumpOntoLedgeAndCollectCoin(t *testing.T) {
+	frames := hold(Input{Right: true}, 10)
+	frames = append(frames, hold(Input{Right: true, Jump: true}, 20)...)
+	frames = append(frames, hold(Input{Right: true}, 12)...)
+	frames = append(frames, hold(Input{}, 60)...)
+	g := newTestGame(t, frames...)
+	run(t, g, len(frames))
+
+	if !g.player.isGrounded || g.player.y+g.player.height != 470 {
+		t.Errorf("player bottom = %v grounded=%v, want standing on the ledge at 470", g.player.y+g.player.height, g.player.isGrounded)
+	}
+	if g.player.x < 250 || g.player.x > 400 {
+		t.Errorf("player.x = %v, want on the ledge (250-400)", g.player.x)
+	}
+	if !g.coins[0].collected || g.score != 10 {
+		t.Errorf("coin collected=%v score=%d, want the coin (score 10)", g.coins[0].collected, g.score)
+	}
+}
+
+// ゴールまで走り、残りコインのボーナスが入ってクリアになる
+func TestReachGoal(t *testing.T) {
+	g := newTestGame(t, hold(Input{Right: true}, 600)...)
+	for range 600 {
+		run(t, g, 1)
+		if g.gameState.current != StatePlaying {
+			break
+		}
+	}
+	if got := g.gameState.current; got != StateCleared {
+		t.Fatalf("gameState = %v at x=%v, want cleared", got, g.player.x)
+	}
+	if g.player.x+g.player.width < 1400 {
+		t.Errorf("cleared at x=%v, before the goal", g.player.x)
+	}
+	if g.score != 50 {
+		t.Errorf("score = %d, want 50 (one coin left x 50)", g.score)
+	}
+	if _, ok := g.topScene().(*resultsScene); !ok {
+		t.Errorf("top scene = %T, want *resultsScene", g.topScene())
+	}
+}
+
+// 画面の下へ落ちながらゴールに触れたフレームはクリアになり、やられにならない
+func TestReachGoalWhileFalling(t *testing.T) {
+	g := newTestGame(t)
+	run(t, g, 10)
+	g.player.x = g.goal.x - g.player.width/2
+	g.player.y = screenHeight + 10 // 床より下
+	g.player.vy = 5
+	elapsed := g.elapsedFrames
+	run(t, g, 1)
+	if got := g.gameState.current; got != StateCleared {
+		t.Fatalf("gameState = %v, want cleared", got)
+	}
+	if g.player.vy == -10 {
+		t.Error("the player got the death bounce in the clear frame")
+	}
+	if g.elapsedFrames != elapsed || g.clearElapsedFrames != elapsed {
+		t.Errorf("elapsedFrames %d, clearElapsedFrames %d; want both %d (the clear frame is not counted)",
+			g.elapsedFrames, g.clearElapsedFrames, elapsed)
+	}
+}
+
+// 結果画面はジャンプを押しっぱなしでは進まず、押し直すと次へ進む
+func TestResultsNeedNewJumpPress(t *testing.T) {
+	frames := hold(Input{Right: true, Jump: true}, 600)
+	frames = append(frames, Input{}, Input{Jump: true})
+	g := newTestGame(t, frames...)
+	for range 600 {
+		run(t, g, 1)
+		if g.gameState.current == StateCleared {
+			break
+		}
+	}
+	if got := g.gameState.current; got != StateCleared {
+		t.Fatalf("gameState = %v, want cleared", got)
+	}
+	for g.input.(*scriptedInput).pos < 600 { // 残りもジャンプを押したまま
+		run(t, g, 1)
+	}
+	if g.clearTime < clearInputDelay {
+		t.Fatalf("only %d frames on the results screen, want at least %d", g.clearTime, clearInputDelay)
+	}
+	if got := g.gameState.current; got != StateCleared {
+		t.Fatalf("holding jump left the results screen (%v)", got)
+	}
+	run(t, g, 2)
+	if got := g.gameState.current; got != StateAllClear {
+		t.Errorf("gameState = %v after pressing jump again, want all clear", got)
+	}
+}
+
+// F7 の設定画面を開いている間は入力を記録せず、リプレイも進めない
+func TestSettingsHotkeyOutsideReplay(t *testing.T) {
+	g := newTestGame(t)
+	run(t, g, 10)
+	n := len(g.recording.Frames)
+	g.toggleSettings()
+	run(t, g, 5)
+	if got := len(g.recording.Frames); got != n {
+		t.Errorf("recorded %d frames while the settings were open", got-n)
+	}
+	g.toggleSettings()
+	if _, ok := g.topScene().(*playScene); !ok {
+		t.Errorf("top scene = %T after closing the settings, want *playScene", g.topScene())
+	}
+
+	// 再生中はプレイヤーの入力（右で music を選び、ジャンプで上げる）で操作する
+	g = newTestGame(t, Input{Right: true}, Input{}, Input{Jump: true})
+	r := &Replay{StageID: "1-1", Physics: defaultPhysics(), Frames: hold(Input{Right: true}, 100)}
+	if err := g.startReplay(r); err != nil {
+		t.Fatal(err)
+	}
+	run(t, g, 10)
+	replay := g.input.(*replayInput)
+	pos := replay.pos
+	g.toggleSettings()
+	run(t, g, 3)
+	if replay.pos != pos {
+		t.Errorf("replay advanced %d frames while the settings were open", replay.pos-pos)
+	}
+	if want := defaultMixer().Music + volumeStep; math.Abs(g.mixer.Music-want) > 1e-9 {
+		t.Errorf("music volume = %v, want %v", g.mixer.Music, want)
+	}
+}
+
+// 同じ入力なら同じ結果になる（リプレイの前提）
+func TestDeterministic(t *testing.T) {
+	var frames []Input
+	for i := range 300 {
+		frames = append(frames, Input{Right: i%50 < 40, Jump: i%30 < 8, Action: i%100 < 50})
+	}
+	a, b := newTestGame(t, frames...), newTestGame(t, frames...)
+	run(t, a, len(frames))
+	run(t, b, len(frames))
+	if a.player.x != b.player.x || a.player.y != b.player.y || a.score != b.score || a.gameState.current != b.gameState.current {
+		t.Errorf("runs diverged: (%v, %v, %d, %v) vs (%v, %v, %d, %v)",
+			a.player.x, a.player.y, a.score, a.gameState.current, b.player.x, b.player.y, b.score, b.gameState.current)
+	}
+}
+
+// jumper の跳ぶ間隔は g.rng で決まり、同じシードなら同じフレームで跳ぶ
+func TestJumperFollowsSeed(t *testing.T) {
+	level := strings.Replace(testLevel, `"enemies": []`,
+		`"enemies": [{ "x": 600, "y": 526, "width": 24, "height": 24, "vx": 0, "kind": "jumper" }]`, 1)
+	hops := func(seed uint64) []int {
+		g := newTestGameWith(t, level)
+		g.setSeed(seed)
+		var frames []int
+		for i := range 1000 {
+			up := g.enemies[0].vy < 0
+			run(t, g, 1)
+			if !up && g.enemies[0].vy < 0 {
+				frames = append(frames, i)
+			}
+		}
+		return frames
+	}
+	a, b, c := hops(1), hops(1), hops(2)
+	if len(a) < 3 {
+		t.Fatalf("jumper hopped %d times in 1000 frames, want several", len(a))
+	}
+	if !slices.Equal(a, b) {
+		t.Errorf("same seed, different hops: %v vs %v", a, b)
+	}
+	if slices.Equal(a, c) {
+		t.Errorf("different seeds, same hops: %v", a)
+	}
+}
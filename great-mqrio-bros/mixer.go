@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"strings"
+)
+
+const (
+	audioSettingsFile    = "audio.json" // saveStorage / loadStorage の名前
+	audioSettingsVersion = 1
+	volumeStep           = 0.1
+)
+
+// Mixer は全体・BGM・効果音の音量（0〜1）とミュート。
+// 実際の音量は全体 × BGM（効果音）で、ミュート中は 0。
+type Mixer struct {
+	Master  float64 `json:"master"`
+	Music   float64 `json:"music"`
+	Effects float64 `json:"effects"`
+	Muted   bool    `json:"muted"`
+}
+
+func defaultMixer() Mixer {
+	return Mixer{Master: 1, Music: 0.6, Effects: 1}
+}
+
+func (m *Mixer) musicVolume() float64 {
+	if m.Muted {
+		return 0
+	}
+	return m.Master * m.Music
+}
+
+func (m *Mixer) effectsVolume() float64 {
+	if m.Muted {
+		return 0
+	}
+	return m.Master * m.Effects
+}
+
+// mixerRows は音量設定画面の行（音量 3 つとミュート）
+var mixerRows = []string{"master", "music", "effects", "mute"}
+
+// volume は音量設定画面の行 i の音量（ミュートの行なら nil）
+func (m *Mixer) volume(i int) *float64 {
+	switch i {
+	case 0:
+		return &m.Master
+	case 1:
+		return &m.Music
+	case 2:
+		return &m.Effects
+	}
+	return nil
+}
+
+// line は音量設定画面の行 i の表示（音量はバー、ミュートは true/false）
+func (m *Mixer) line(i int) string {
+	if v := m.volume(i); v != nil {
+		steps := int(math.Round(*v / volumeStep))
+		return fmt.Sprintf("%-8s [%-10s] %3.0f%%", mixerRows[i], strings.Repeat("#", steps), *v*100)
+	}
+	return fmt.Sprintf("%-8s %v", mixerRows[i], m.Muted)
+}
+
+// applyVolume は今の音量を BGM と効果音に反映する
+func (g *Game) applyVolume() {
+	if g.bgm != nil {
+		g.bgm.SetVolume(g.mixer.musicVolume())
+	}
+	if g.sounds != nil {
+		g.sounds.setVolume(g.mixer.effectsVolume())
+	}
+}
+
+// adjustVolume は行 i の音量を d だけ変える（ミュートの行なら切り替える）
+func (g *Game) adjustVolume(i int, d float64) {
+	v := g.mixer.volume(i)
+	if v == nil {
+		g.toggleMute()
+		return
+	}
+	*v = min(max(math.Round((*v+d)/volumeStep)*volumeStep, 0), 1)
+	g.audioChanged = true
+	g.applyVolume()
+}
+
+// toggleMute はミュートを切り替える。設定画面を開いていなければすぐ保存する（開いていれば閉じるときに保存）。
+func (g *Game) toggleMute() {
+	g.mixer.Muted = !g.mixer.Muted
+	g.applyVolume()
+	if g.mixer.Muted {
+		g.showNotice("MUTE")
+	} else {
+		g.showNotice("SOUND ON")
+	}
+	if _, open := g.topScene().(*settingsScene); open {
+		g.audioChanged = true
+	} else {
+		g.saveAudioSettings()
+	}
+}
+
+// loadAudioSettings は保存済みの音量を読む。読めなければ標準の音量で始める。
+func (g *Game) loadAudioSettings() {
+	data, err := loadStorage(audioSettingsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var m Mixer
+	if err == nil {
+		m, err = decodeAudioSettings(data)
+	}
+	if err != nil {
+		log.Printf("%s: %v", audioSettingsFile, err)
+		g.showNotice("AUDIO SETTINGS LOAD FAILED")
+		return
+	}
+	g.mixer = m
+	g.applyVolume()
+}
+
+// audioSettingsData は音量設定ファイルの形式
+type audioSettingsData struct {
+	Version int `json:"version"`
+	Mixer
+}
+
+// decodeAudioSettings は音量設定ファイルを読み、書かれていない値は標準の音量にする
+func decodeAudioSettings(data []byte) (Mixer, error) {
+	f := audioSettingsData{Mixer: defaultMixer()}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Mixer{}, err
+	}
+	if f.Version != audioSettingsVersion {
+		return Mixer{}, fmt.Errorf("unsupported version %d (want %d)", f.Version, audioSettingsVersion)
+	}
+	m := f.Mixer
+	for i := range mixerRows {
+		if v := m.volume(i); v != nil {
+			*v = min(max(*v, 0), 1)
+		}
+	}
+	return m, nil
+}
+
+// saveAudioSettings は今の音量を保存する
+func (g *Game) saveAudioSettings() {
+	data, err := json.MarshalIndent(audioSettingsData{Version: audioSettingsVersion, Mixer: g.mixer}, "", "  ")
+	if err == nil {
+		err = saveStorage(audioSettingsFile, append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("%s: %v", audioSettingsFile, err)
+		g.showNotice("AUDIO SETTINGS SAVE FAILED")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import "math"
+
+// Physics はプレイヤーの動きの調整値。
+// 敵やアイテムの重力は定数 gravity のままで、ここを変えてもプレイヤーにしか効かない。
+type Physics struct {
+	Gravity          float64 `json:"gravity"`
+	MaxFallSpeed     float64 `json:"maxFallSpeed"`
+	MoveSpeed        float64 `json:"moveSpeed"`
+	RunSpeed         float64 `json:"runSpeed"`         // Action を押して走っているときの最高速
+	RunJumpBonus     float64 `json:"runJumpBonus"`     // 最高速で走っているときにジャンプ力に足す量（速さに比例）
+	SkidDecel        float64 `json:"skidDecel"`        // 走っている向きと逆に入力したときに 1 フレームに遅くなる量
+	Acceleration     float64 `json:"acceleration"`     // 入力した向きへ 1 フレームに速くなる量
+	Friction         float64 `json:"friction"`         // 入力がないときに 1 フレームに遅くなる量
+	JumpPower        float64 `json:"jumpPower"`        // ジャンプした瞬間の vy（負で上向き）
+	JumpCut          float64 `json:"jumpCut"`          // 上昇中にジャンプを離したときに vy に掛ける値（1 なら常に最大の高さ）
+	CoyoteFrames     int     `json:"coyoteFrames"`     // 足場の端から歩いて落ちた後もジャンプできるフレーム数
+	JumpBufferFrames int     `json:"jumpBufferFrames"` // 着地の少し前に押したジャンプを着地時まで覚えておくフレーム数
+}
+
+// defaultPhysics は標準の調整値を返す
+func defaultPhysics() Physics {
+	return Physics{
+		Gravity:          gravity,
+		MaxFallSpeed:     15,
+		MoveSpeed:        moveSpeed,
+		RunSpeed:         6.5,
+		RunJumpBonus:     2,
+		SkidDecel:        0.6,
+		Acceleration:     0.3,
+		Friction:         0.25,
+		JumpPower:        jumpPower,
+		JumpCut:          0.5,
+		CoyoteFrames:     6,
+		JumpBufferFrames: 6,
+	}
+}
+
+// approach は v を target へ最大 step だけ近づけた値を返す
+func approach(v, target, step float64) float64 {
+	if v < target {
+		return min(v+step, target)
+	}
+	return max(v-step, target)
+}
+
+// updateWalk は左右の入力で横の速さを変える。入力した向きへは Acceleration で加速し、
+// 入力がなければ Friction で止まり、走っている向きと逆に入力すると SkidDecel でブレーキをかける。
+// 地面の上では足場の滑りやすさ（氷など）を掛ける。水中では走れず swimSpeed まで。
+func (g *Game) updateWalk(left, right, run bool) {
+	p := &g.player
+	ph := &g.physics
+
+	speed := ph.MoveSpeed
+	switch {
+	case p.inWater:
+		speed = swimSpeed
+	case run:
+		speed = ph.RunSpeed
+	}
+	target := 0.0
+	if left {
+		target = -speed
+		p.isFacingRight = false
+	}
+	if right {
+		target = speed
+		p.isFacingRight = true
+	}
+
+	traction := 1.0
+	if p.isGrounded && p.ground >= 0 {
+		traction = g.platforms[p.ground].traction()
+	}
+	p.isSkidding = p.isGrounded && target*p.vx < 0
+	rate := ph.Acceleration
+	switch {
+	case p.isSkidding:
+		rate = ph.SkidDecel
+	case target == 0 || math.Abs(p.vx) > math.Abs(target):
+		rate = ph.Friction // 離したとき・走りをやめたとき
+	}
+	p.vx = approach(p.vx, target, rate*traction)
+}
+
+// runJumpPower は今の横の速さでのジャンプ力を返す（歩きより速い分だけ RunJumpBonus に近づく）
+func (g *Game) runJumpPower() float64 {
+	ph := &g.physics
+	t := 0.0
+	if ph.RunSpeed > ph.MoveSpeed {
+		t = (math.Abs(g.player.vx) - ph.MoveSpeed) / (ph.RunSpeed - ph.MoveSpeed)
+	}
+	return ph.JumpPower - ph.RunJumpBonus*min(max(t, 0), 1)
+}
+
+// playerAnimRate は 1 フレームにアニメーションを進める量（歩きは走る速さに比例して速くなる）
+func (g *Game) playerAnimRate() float64 {
+	if g.player.state.current != PlayerWalk || g.physics.MoveSpeed <= 0 {
+		return 1
+	}
+	return math.Abs(g.player.vx) / g.physics.MoveSpeed
+}
+
+// updateJump はジャンプの入力を処理する。pressed はこのフレームにジャンプを押した瞬間か、held は押し続けているか。
+// 地面を離れてから CoyoteFrames の間と、着地の JumpBufferFrames 前までに押したジャンプも受け付け、
+// 上昇中にジャンプを離すと JumpCut の分だけ低いジャンプになる。ジャンプしたら true を返す。
+func (g *Game) updateJump(pressed, held bool) bool {
+	p := &g.player
+	ph := &g.physics
+
+	// どちらも「あと何フレーム有効か」を数える（0 なら無効）。
+	// 設定が 0 のときも、そのフレームだけは有効になるよう 1 足しておく。
+	if p.isGrounded {
+		p.coyoteTime = ph.CoyoteFrames + 1
+	} else if p.coyoteTime > 0 {
+		p.coyoteTime--
+	}
+	if pressed {
+		p.jumpBuffer = ph.JumpBufferFrames + 1
+	} else if p.jumpBuffer > 0 {
+		p.jumpBuffer--
+	}
+
+	if !held && p.jumpHeld && p.vy < 0 {
+		p.vy *= ph.JumpCut
+	}
+	if !held || p.vy >= 0 {
+		p.jumpHeld = false
+	}
+
+	if p.jumpBuffer == 0 || p.coyoteTime == 0 {
+		return false
+	}
+	p.vy = g.runJumpPower()
+	p.isGrounded = false
+	p.jumpBuffer = 0
+	p.coyoteTime = 0
+	p.jumpHeld = true
+	return true
+}
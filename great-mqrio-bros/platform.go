@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	crumbleFrames        = 30 // 崩れる足場に乗ってから落ち始めるまでのフレーム数
+	defaultPlatformSpeed = 1  // 動く足場の speed 省略時の速さ（px/フレーム）
+)
+
+// PlatformKind は足場の種類
+type PlatformKind int
+
+const (
+	PlatformSolid    PlatformKind = iota // 上下左右どこからもぶつかる
+	PlatformOneWay                       // 上からだけ乗れる。下からはジャンプですり抜ける
+	PlatformCrumble                      // 乗ると少しして崩れ落ちる
+	PlatformQuestion                     // 下から叩くと中身が出て使用済みになる
+	PlatformBrick                        // 下から叩くと跳ね、大きい状態なら壊れる
+)
+
+// Surface は足場の表面の種類（滑りやすさ）
+type Surface int
+
+const (
+	SurfaceNormal Surface = iota
+	SurfaceIce            // 加速・減速・ブレーキが効きにくい
+)
+
+// surfaceTraction は表面ごとに加速・減速に掛ける値
+var surfaceTraction = map[Surface]float64{
+	SurfaceNormal: 1,
+	SurfaceIce:    0.15,
+}
+
+// parseSurface はレベルファイルの "surface" を表面の種類に変換する（省略時は normal）
+func parseSurface(s string) (Surface, error) {
+	switch s {
+	case "", "normal":
+		return SurfaceNormal, nil
+	case "ice":
+		return SurfaceIce, nil
+	}
+	return 0, fmt.Errorf("unknown surface %q (want normal or ice)", s)
+}
+
+// parsePlatformKind はレベルファイルの "kind" を足場の種類に変換する（省略時は solid）
+func parsePlatformKind(s string) (PlatformKind, error) {
+	switch s {
+	case "", "solid":
+		return PlatformSolid, nil
+	case "oneway":
+		return PlatformOneWay, nil
+	case "crumble":
+		return PlatformCrumble, nil
+	case "question":
+		return PlatformQuestion, nil
+	case "brick":
+		return PlatformBrick, nil
+	}
+	return 0, fmt.Errorf("unknown platform kind %q (want solid, oneway, crumble, question or brick)", s)
+}
+
+// reset は足場を最初の位置・状態に戻す
+func (p *Platform) reset() {
+	p.x = p.initialX
+	p.y = p.initialY
+	p.dx, p.dy = 0, 0
+	p.target = 0
+	p.crumbleTime = 0
+	p.falling = false
+	p.vy = 0
+	p.used = false
+	p.broken = false
+	p.bumpTime = 0
+}
+
+// isSolid は今この足場に乗ったりぶつかったりできるか（崩れ落ちている足場・壊れたレンガはすり抜ける）
+func (p *Platform) isSolid() bool {
+	return !p.falling && !p.broken
+}
+
+// traction はこの足場の上での加速・減速の効きやすさ（1 が普通）
+func (p *Platform) traction() float64 {
+	return surfaceTraction[p.surface]
+}
+
+// shake は崩れかけの足場を揺らして描くための横方向のずれ
+func (p *Platform) shake() float64 {
+	if p.crumbleTime == 0 || p.falling {
+		return 0
+	}
+	return float64(p.crumbleTime/2%2*2 - 1)
+}
+
+// resetPlatforms はすべての足場を最初の状態に戻す
+func (g *Game) resetPlatforms() {
+	for i := range g.platforms {
+		g.platforms[i].reset()
+		g.platformGrid.Update(i, g.platforms[i].rect())
+	}
+	g.player.ground = -1
+}
+
+// updatePlatforms は動く足場を経路に沿って進め、崩れる足場を落とす。
+// このフレームに動いた量を dx, dy に残し、乗っているプレイヤーを運ぶのに使う。
+func (g *Game) updatePlatforms() {
+	for i := range g.platforms {
+		p := &g.platforms[i]
+		oldX, oldY := p.x, p.y
+
+		if len(p.path) > 0 && !p.falling {
+			// 出発点 → path[0] → path[1] → … → 出発点 の順に一定の速さで回る
+			tx, ty := p.initialX, p.initialY
+			if p.target < len(p.path) {
+				tx, ty = p.path[p.target].X, p.path[p.target].Y
+			}
+			dx, dy := tx-p.x, ty-p.y
+			dist := math.Hypot(dx, dy)
+			if dist <= p.speed {
+				p.x, p.y = tx, ty
+				p.target = (p.target + 1) % (len(p.path) + 1)
+			} else {
+				p.x += dx / dist * p.speed
+				p.y += dy / dist * p.speed
+			}
+		}
+
+		if p.crumbleTime > 0 && !p.falling {
+			p.crumbleTime++
+			if p.crumbleTime >= crumbleFrames {
+				p.falling = true
+			}
+		}
+		if p.falling && p.y <= screenHeight {
+			p.vy = min(p.vy+gravity, 15)
+			p.y += p.vy
+		}
+
+		p.dx, p.dy = p.x-oldX, p.y-oldY
+		if p.dx != 0 || p.dy != 0 {
+			g.platformGrid.Update(i, p.rect())
+		}
+	}
+}
+
+// standOn はプレイヤーが足場 i に乗ったときの処理（崩れる足場のカウントを始める）
+func (g *Game) standOn(i int) {
+	p := &g.platforms[i]
+	if p.kind == PlatformCrumble && p.crumbleTime == 0 {
+		p.crumbleTime = 1
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+)
+
+const (
+	bigPlayerHeight   = 72  // 大きい状態のプレイヤーの高さ（小さい状態は playerHeight）
+	invincibleFrames  = 120 // ダメージで小さくなった後の無敵フレーム数
+	itemSize          = 24
+	itemSpeed         = 2 // キノコの移動速度
+	fireballSize      = 12
+	fireballSpeed     = 7
+	fireballBounce    = -5 // 足場で跳ねるときの vy
+	maxFireballs      = 2  // 画面上に同時に出せるファイアボールの数
+	powerUpScore      = 1000
+	fireballKillScore = 100
+)
+
+// PowerLevel はプレイヤーのパワーアップ段階
+type PowerLevel int
+
+const (
+	PowerSmall PowerLevel = iota // 通常
+	PowerBig                     // キノコで大きくなった
+	PowerFire                    // フラワーでファイアボールが撃てる
+)
+
+// ItemKind はパワーアップアイテムの種類
+type ItemKind int
+
+const (
+	ItemMushroom ItemKind = iota // 取ると大きくなる。横に歩いて足場から落ちる
+	ItemFlower                   // 取るとファイア状態になる。その場から動かない
+)
+
+// parseItemKind はレベルファイルの "kind" をアイテムの種類に変換する
+func parseItemKind(s string) (ItemKind, error) {
+	switch s {
+	case "mushroom":
+		return ItemMushroom, nil
+	case "flower":
+		return ItemFlower, nil
+	}
+	return 0, fmt.Errorf("unknown item kind %q (want mushroom or flower)", s)
+}
+
+// Item はパワーアップアイテムの構造体
+type Item struct {
+	kind     ItemKind
+	x, y     float64
+	vx, vy   float64
+	active   bool // 出現中（取られたり画面外に落ちたら false）
+	spawned  bool // ハテナブロックから出た（ステージをやり直すと消える）
+	initialX float64
+	initialY float64
+}
+
+// reset はアイテムを出現位置に戻す
+func (it *Item) reset() {
+	it.active = true
+	it.x = it.initialX
+	it.y = it.initialY
+	it.vx = itemSpeed
+	it.vy = 0
+}
+
+// removeSpawnedItems はハテナブロックから出たアイテムを取り除き、レベルファイルのアイテムだけにする
+func (g *Game) removeSpawnedItems() {
+	g.items = slices.DeleteFunc(g.items, func(it Item) bool { return it.spawned })
+}
+
+// Fireball はプレイヤーが撃つ弾の構造体
+type Fireball struct {
+	x, y   float64
+	vx, vy float64
+	active bool
+}
+
+// setPower はプレイヤーのパワーアップ段階を変え、足元の位置を保ったまま当たり判定の高さを変える。
+// 大きくなるときに頭の上が足場で塞がっていれば、足場にめり込まないよう空くまで待つ（pendingPower）。
+func (g *Game) setPower(p PowerLevel) {
+	height := float64(playerHeight)
+	if p != PowerSmall {
+		height = bigPlayerHeight
+	}
+	if height > g.player.height && !g.hasHeadroom(height) {
+		g.player.pendingPower = p
+		return
+	}
+	g.player.pendingPower = PowerSmall
+	g.player.y += g.player.height - height
+	g.player.height = height
+	g.player.power = p
+}
+
+// updatePendingPower は頭の上が空いたら、待っていたパワーアップで大きくなる
+func (g *Game) updatePendingPower() {
+	if g.player.pendingPower != PowerSmall {
+		g.setPower(g.player.pendingPower)
+	}
+}
+
+// hasHeadroom は足元の位置を保ったまま高さを height にしても、すり抜けられない足場に重ならないか
+func (g *Game) hasHeadroom(height float64) bool {
+	p := &g.player
+	r := collision.Rect{X: p.x, Y: p.y + p.height - height, W: p.width, H: height}
+	for _, s := range g.platformsNear(r) {
+		if !s.OneWay && r.Overlaps(s.Rect) {
+			return false
+		}
+	}
+	return true
+}
+
+// hurtPlayer は敵に横から当たったときの処理。大きければ小さくなって無敵時間、小さければやられ。
+// やられた場合は true を返す。
+func (g *Game) hurtPlayer() bool {
+	if g.player.invincible > 0 {
+		return false
+	}
+	if g.player.power == PowerSmall {
+		g.killPlayer()
+		return true
+	}
+	g.setPower(PowerSmall)
+	g.player.invincible = invincibleFrames
+	g.playSound(SoundEnemy)
+	return false
+}
+
+// moveBody は重力のかかる小さな物体（アイテム・ファイアボール）を動かし、足場で止める。
+// 横方向に足場へぶつかったら hitWall、足場に着地したら landed を返す。
+func (g *Game) moveBody(x, y, vx, vy *float64, w, h float64) (hitWall, landed bool) {
+	*vy += gravity
+	if *vy > 15 {
+		*vy = 15
+	}
+
+	r := collision.Rect{X: *x, Y: *y, W: w, H: h}
+	res := collision.Move(r, *vx, *vy, g.platformsNear(r.Swept(*vx, *vy)))
+	*x, *y = res.X, res.Y
+	if res.Floor || res.Ceiling {
+		*vy = 0
+	}
+	return res.Left || res.Right, res.Floor
+}
+
+// updateItems はアイテムを動かし、プレイヤーが触れたらパワーアップさせる
+func (g *Game) updateItems() {
+	for i := range g.items {
+		it := &g.items[i]
+		if !it.active {
+			continue
+		}
+		if it.kind == ItemMushroom {
+			if hitWall, _ := g.moveBody(&it.x, &it.y, &it.vx, &it.vy, itemSize, itemSize); hitWall {
+				it.vx = -it.vx
+			}
+			if it.y > screenHeight {
+				it.active = false
+				continue
+			}
+		}
+
+		if g.player.x+g.player.width <= it.x || g.player.x >= it.x+itemSize ||
+			g.player.y+g.player.height <= it.y || g.player.y >= it.y+itemSize {
+			continue
+		}
+		it.active = false
+		g.score += powerUpScore
+		g.checkExtraLife()
+		switch it.kind {
+		case ItemMushroom:
+			if g.player.power == PowerSmall && g.player.pendingPower == PowerSmall {
+				g.setPower(PowerBig)
+			}
+		case ItemFlower:
+			g.setPower(PowerFire)
+		}
+		g.playSound(SoundPowerUp)
+	}
+}
+
+// shootFireball はファイア状態のときにプレイヤーの向きへファイアボールを撃つ
+func (g *Game) shootFireball() {
+	if g.player.power != PowerFire {
+		return
+	}
+	active := 0
+	for _, f := range g.fireballs {
+		if f.active {
+			active++
+		}
+	}
+	if active >= maxFireballs {
+		return
+	}
+
+	f := Fireball{
+		x:      g.player.x + g.player.width,
+		y:      g.player.y + g.player.height/3,
+		vx:     fireballSpeed,
+		active: true,
+	}
+	if !g.player.isFacingRight {
+		f.x = g.player.x - fireballSize
+		f.vx = -fireballSpeed
+	}
+	// 使い終わった枠を再利用する
+	for i := range g.fireballs {
+		if !g.fireballs[i].active {
+			g.fireballs[i] = f
+			return
+		}
+	}
+	g.fireballs = append(g.fireballs, f)
+}
+
+// updateFireballs はファイアボールを跳ねながら進め、当たった敵を倒す
+func (g *Game) updateFireballs() {
+	for i := range g.fireballs {
+		f := &g.fireballs[i]
+		if !f.active {
+			continue
+		}
+		hitWall, landed := g.moveBody(&f.x, &f.y, &f.vx, &f.vy, fireballSize, fireballSize)
+		if landed {
+			f.vy = fireballBounce
+		}
+		if hitWall || f.y > screenHeight ||
+			f.x < g.cameraX-fireballSize || f.x > g.cameraX+screenWidth {
+			f.active = false
+			continue
+		}
+
+		g.nearby = g.enemyGrid.Query(collision.Rect{X: f.x, Y: f.y, W: fireballSize, H: fireballSize}, g.nearby[:0])
+		for _, j := range g.nearby {
+			e := &g.enemies[j]
+			if !e.isAlive ||
+				f.x+fireballSize <= e.x || f.x >= e.x+e.width ||
+				f.y+fireballSize <= e.y || f.y >= e.y+e.height {
+				continue
+			}
+			f.active = false
+			g.defeatEnemy(e, fireballKillScore)
+			break
+		}
+	}
+}
+
+// drawItems はアイテムとファイアボールを描画する
+func (g *Game) drawItems(screen *ebiten.Image, cam float32) {
+	for _, it := range g.items {
+		if !it.active {
+			continue
+		}
+		switch it.kind {
+		case ItemMushroom:
+			// 赤いかさ＋肌色の柄
+			vector.DrawFilledRect(screen, float32(it.x)-cam, float32(it.y), itemSize, itemSize/2,
+				color.RGBA{R: 220, G: 40, B: 40, A: 255}, false)
+			vector.DrawFilledRect(screen, float32(it.x+6)-cam, float32(it.y+itemSize/2), itemSize-12, itemSize/2,
+				color.RGBA{R: 255, G: 220, B: 177, A: 255}, false)
+		case ItemFlower:
+			// オレンジの花＋緑の茎
+			vector.DrawFilledCircle(screen, float32(it.x+itemSize/2)-cam, float32(it.y+8), 8,
+				color.RGBA{R: 255, G: 140, B: 0, A: 255}, false)
+			vector.DrawFilledRect(screen, float32(it.x+itemSize/2-2)-cam, float32(it.y+14), 4, itemSize-14,
+				color.RGBA{R: 40, G: 160, B: 40, A: 255}, false)
+		}
+	}
+
+	for _, f := range g.fireballs {
+		if !f.active {
+			continue
+		}
+		vector.DrawFilledCircle(screen, float32(f.x+fireballSize/2)-cam, float32(f.y+fireballSize/2), fireballSize/2,
+			color.RGBA{R: 255, G: 120, B: 0, A: 255}, false)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// 低い天井の下でキノコを取っても足場にめり込まず、天井を抜けてから大きくなる
+func TestGrowWaitsForHeadroom(t *testing.T) {
+	// 床（y=550）との隙間が 60px の天井（小さいプレイヤーは入れるが大きいと入らない）
+	level := strings.Replace(testLevel, `"platforms": [`,
+		`"platforms": [
+    { "x": 0, "y": 470, "width": 200, "height": 20 },`, 1)
+	g := newTestGameWith(t, level, append(hold(Input{}, 30), hold(Input{Right: true}, 60)...)...)
+	run(t, g, 30)
+
+	g.setPower(PowerBig)
+	if g.player.power != PowerSmall || g.player.height != playerHeight {
+		t.Fatalf("grew under a low ceiling: power %v height %v", g.player.power, g.player.height)
+	}
+	if g.player.pendingPower != PowerBig {
+		t.Fatalf("pendingPower = %v, want big", g.player.pendingPower)
+	}
+
+	for range 60 {
+		run(t, g, 1)
+		ceiling := g.platforms[0].rect()
+		if g.player.rect().Overlaps(ceiling) {
+			t.Fatalf("player %+v overlaps the ceiling %+v", g.player.rect(), ceiling)
+		}
+	}
+	if g.player.x < 200 {
+		t.Fatalf("player.x = %v, still under the ceiling", g.player.x)
+	}
+	if g.player.power != PowerBig || g.player.height != bigPlayerHeight || g.player.pendingPower != PowerSmall {
+		t.Errorf("after leaving the ceiling: power %v height %v pending %v, want big", g.player.power, g.player.height, g.player.pendingPower)
+	}
+	if bottom := g.player.y + g.player.height; bottom != 550 {
+		t.Errorf("feet at %v after growing, want 550", bottom)
+	}
+}
+
+// 頭の上が空いていればすぐに大きくなり、ダメージで小さくなる
+func TestGrowAndShrink(t *testing.T) {
+	g := newTestGame(t)
+	run(t, g, 30)
+	g.setPower(PowerFire)
+	if g.player.power != PowerFire || g.player.height != bigPlayerHeight || g.player.y+g.player.height != 550 {
+		t.Fatalf("power %v height %v bottom %v, want fire, big, feet on the floor", g.player.power, g.player.height, g.player.y+g.player.height)
+	}
+	if g.hurtPlayer() {
+		t.Fatal("a big player died from one hit")
+	}
+	if g.player.power != PowerSmall || g.player.height != playerHeight || g.player.invincible == 0 {
+		t.Errorf("after a hit: power %v height %v invincible %d, want small and invincible", g.player.power, g.player.height, g.player.invincible)
+	}
+}
+
+// 中間地点より前にブロックから出して取っていないアイテムは、やられて中間地点から再開しても残る
+func TestCheckpointKeepsReleasedItems(t *testing.T) {
+	level := strings.Replace(testLevel, `{ "x": 250, "y": 470, "width": 150, "height": 20 }`,
+		`{ "x": 250, "y": 470, "width": 150, "height": 20, "kind": "question", "contents": "mushroom" },
+    { "x": 450, "y": 470, "width": 30, "height": 20, "kind": "question", "contents": "flower" }`, 1)
+	level = strings.Replace(level, `"enemies": []`, `"enemies": [], "checkpoints": [{ "x": 600, "y": 550 }]`, 1)
+	g := newTestGameWith(t, level)
+	run(t, g, 1)
+
+	g.bumpBlock(1) // 中間地点の前に出したキノコ
+	g.player.x = 600
+	g.updateCheckpoints()
+	if g.activeCheckpoint != 0 {
+		t.Fatal("checkpoint not reached")
+	}
+	g.bumpBlock(2) // 中間地点の後に出したフラワー
+	if len(g.items) != 2 {
+		t.Fatalf("%d items released, want 2", len(g.items))
+	}
+
+	g.respawn()
+	if len(g.items) != 1 || g.items[0].kind != ItemMushroom || !g.items[0].active {
+		t.Fatalf("items after the respawn = %+v, want the mushroom only", g.items)
+	}
+	if !g.platforms[1].used || g.platforms[2].used {
+		t.Errorf("blocks used %v %v, want the first one only", g.platforms[1].used, g.platforms[2].used)
+	}
+}
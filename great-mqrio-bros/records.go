@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	recordsFile    = "records.json" // saveStorage / loadStorage の名前
+	recordsVersion = 1
+	maxRecords     = 5 // ステージごとに残すスコア・タイムの数
+	initialsLength = 3
+)
+
+// Record はステージを 1 回クリアしたときの記録
+type Record struct {
+	Score    int    `json:"score"`              // そのステージで取ったスコア（コインボーナスを含む）
+	Frames   int    `json:"frames"`             // クリアタイム（フレーム数）
+	Date     string `json:"date"`               // クリアした日（2006-01-02）
+	Initials string `json:"initials,omitempty"` // 入力しなければ空
+}
+
+// StageRecords は 1 つのステージの上位の記録（スコアは高い順、タイムは短い順）
+type StageRecords struct {
+	Scores []Record `json:"scores"`
+	Times  []Record `json:"times"`
+}
+
+// Records はステージ ID（"1-1" など）ごとの記録
+type Records map[string]*StageRecords
+
+// add は stage のクリア記録 r を足し、スコア・タイムの順位（0 が 1 位、上位に入らなければ -1）を返す
+func (rs Records) add(stage string, r Record) (scoreRank, timeRank int) {
+	s := rs[stage]
+	if s == nil {
+		s = &StageRecords{}
+		rs[stage] = s
+	}
+	s.Scores, scoreRank = insertRecord(s.Scores, r, func(a, b Record) bool { return a.Score > b.Score })
+	s.Times, timeRank = insertRecord(s.Times, r, func(a, b Record) bool { return a.Frames < b.Frames })
+	return scoreRank, timeRank
+}
+
+// insertRecord は better の順に並んだ list に r を入れ、上位 maxRecords 件だけ残す。
+// 同じ値なら先に出した記録を上にする。
+func insertRecord(list []Record, r Record, better func(a, b Record) bool) ([]Record, int) {
+	i := 0
+	for i < len(list) && !better(r, list[i]) {
+		i++
+	}
+	if i >= maxRecords {
+		return list, -1
+	}
+	list = slices.Insert(list, i, r)
+	if len(list) > maxRecords {
+		list = list[:maxRecords]
+	}
+	return list, i
+}
+
+// recordsData は記録ファイルの形式
+type recordsData struct {
+	Version int     `json:"version"`
+	Stages  Records `json:"stages"`
+}
+
+// decodeRecords は記録ファイルを読む。ありえない記録（負のスコア・0 フレーム以下のタイム・長すぎるイニシャル）があれば壊れているとみなす。
+func decodeRecords(data []byte) (Records, error) {
+	var f recordsData
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != recordsVersion {
+		return nil, fmt.Errorf("unsupported version %d (want %d)", f.Version, recordsVersion)
+	}
+	rs := Records{}
+	for id, s := range f.Stages {
+		if s == nil {
+			continue
+		}
+		for _, r := range append(slices.Clip(s.Scores), s.Times...) {
+			if r.Score < 0 || r.Frames <= 0 || len(r.Initials) > initialsLength {
+				return nil, fmt.Errorf("stage %s: bad record %+v", id, r)
+			}
+		}
+		s.Scores = s.Scores[:min(len(s.Scores), maxRecords)]
+		s.Times = s.Times[:min(len(s.Times), maxRecords)]
+		rs[id] = s
+	}
+	return rs, nil
+}
+
+// loadRecords は保存済みの記録を読む。読めなければ記録なしで始める。
+func (g *Game) loadRecords() {
+	g.records = Records{}
+	data, err := loadStorage(recordsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var rs Records
+	if err == nil {
+		rs, err = decodeRecords(data)
+	}
+	if err != nil {
+		log.Printf("%s: %v", recordsFile, err)
+		g.showNotice("RECORDS LOAD FAILED")
+		return
+	}
+	g.records = rs
+}
+
+// saveRecords は今の記録を保存する
+func (g *Game) saveRecords() {
+	data, err := json.MarshalIndent(recordsData{Version: recordsVersion, Stages: g.records}, "", "  ")
+	if err == nil {
+		err = saveStorage(recordsFile, append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("%s: %v", recordsFile, err)
+		g.showNotice("RECORDS SAVE FAILED")
+	}
+}
+
+// ClearRecord は今クリアしたステージの記録がスコア・タイムの何位に入ったか（-1 なら入っていない）
+type ClearRecord struct {
+	scoreRank, timeRank int
+}
+
+// ranked は上位に入ったか（イニシャルを入力できる）
+func (c ClearRecord) ranked() bool {
+	return c.scoreRank >= 0 || c.timeRank >= 0
+}
+
+// recordClear はクリアした記録を足して保存する（ゴールしてコインボーナスを足した後に呼ぶ）。
+// ヘッドレス実行（records が nil）とリプレイ再生中は記録しない。
+func (g *Game) recordClear() {
+	g.clearRecord = ClearRecord{scoreRank: -1, timeRank: -1}
+	if g.records == nil {
+		return
+	}
+	if _, replaying := g.input.(*replayInput); replaying {
+		return
+	}
+	r := Record{
+		Score:  g.score - g.stageStartScore,
+		Frames: g.clearElapsedFrames,
+		Date:   time.Now().Format(time.DateOnly),
+	}
+	g.clearRecord.scoreRank, g.clearRecord.timeRank = g.records.add(g.stageID(), r)
+	if g.clearRecord.ranked() {
+		g.saveRecords()
+	}
+}
+
+// setInitials は今クリアした記録にイニシャルを付けて保存する
+func (g *Game) setInitials(initials string) {
+	c := g.clearRecord
+	s := g.records[g.stageID()]
+	if c.scoreRank >= 0 {
+		s.Scores[c.scoreRank].Initials = initials
+	}
+	if c.timeRank >= 0 {
+		s.Times[c.timeRank].Initials = initials
+	}
+	g.saveRecords()
+}
+
+// initialsScene は上位に入った記録のイニシャル入力（結果画面で次へ進もうとしたときに積む）。
+// 左右で文字を選び、ジャンプで決めて次の文字へ、アクションで 1 文字戻り、一時停止で入力を終える。
+//
+// 入力はリプレイに記録しない。再生中は記録を付けないのでこの画面も出ず、
+// 記録には次へ進んだジャンプの入力だけが残る。閉じると then でそのジャンプの処理を続ける。
+type initialsScene struct {
+	g       *Game
+	letters []byte // 最後の 1 文字が選んでいる文字
+	trigger Input  // 次へ進もうとしたフレームの入力
+	then    func() // 入力を終えた後に続ける処理
+}
+
+func newInitialsScene(g *Game, trigger Input, then func()) *initialsScene {
+	return &initialsScene{g: g, letters: []byte{'A'}, trigger: trigger, then: then}
+}
+
+func (s *initialsScene) opaque() bool { return false }
+
+func (s *initialsScene) update(in, prev Input) error {
+	last := len(s.letters) - 1
+	switch {
+	case in.Left && !prev.Left:
+		s.letters[last] = 'A' + (s.letters[last]-'A'+25)%26
+	case in.Right && !prev.Right:
+		s.letters[last] = 'A' + (s.letters[last]-'A'+1)%26
+	case in.Action && !prev.Action:
+		if last > 0 {
+			s.letters = s.letters[:last]
+		}
+	case in.Jump && !prev.Jump:
+		if len(s.letters) == initialsLength {
+			s.finish(string(s.letters))
+		} else {
+			s.letters = append(s.letters, 'A')
+		}
+	case in.Pause && !prev.Pause:
+		s.finish(string(s.letters[:last])) // 選んでいる途中の文字は使わない
+	}
+	return nil
+}
+
+// finish はイニシャルを保存して閉じ、次へ進む
+func (s *initialsScene) finish(initials string) {
+	g := s.g
+	g.setInitials(initials)
+	g.popScene()
+	g.prevInput = s.trigger
+	s.then()
+}
+
+func (s *initialsScene) draw(screen *ebiten.Image) {
+	const w, h = 260, 110
+	x, y := float32(screenWidth-w)/2, float32(screenHeight-h)/2
+	vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{A: 230}, false)
+	name := string(s.letters) + strings.Repeat("_", initialsLength-len(s.letters))
+	b := s.g.bindings
+	msg := fmt.Sprintf("ENTER YOUR INITIALS\n\n    %s\n\n%s/%s = letter, %s = OK\n%s = back, %s = done",
+		name, b.firstKey(ControlLeft), b.firstKey(ControlRight), b.firstKey(ControlJump), b.firstKey(ControlAction), b.firstKey(ControlPause))
+	ebitenutil.DebugPrintAt(screen, msg, int(x)+12, int(y)+8)
+}
+
+// drawRecords は今のステージのスコア・タイムの上位を (x, y) から 2 列で描き、今回入った記録に色を付ける
+func (g *Game) drawRecords(screen *ebiten.Image, x, y int) {
+	s := g.records[g.stageID()]
+	if s == nil {
+		return
+	}
+	const colWidth, lineHeight = 220, 16
+	highlight := color.RGBA{R: 255, G: 215, A: 120}
+	scores, times := []string{"BEST SCORES"}, []string{"BEST TIMES"}
+	for i, r := range s.Scores {
+		scores = append(scores, fmt.Sprintf("%d. %-3s %6d  %s", i+1, r.Initials, r.Score, r.Date))
+	}
+	for i, r := range s.Times {
+		times = append(times, fmt.Sprintf("%d. %-3s %6.2fs %s", i+1, r.Initials, float64(r.Frames)/60, r.Date))
+	}
+	for col, rank := range []int{g.clearRecord.scoreRank, g.clearRecord.timeRank} {
+		if rank >= 0 {
+			vector.DrawFilledRect(screen, float32(x+col*colWidth-4), float32(y+(rank+1)*lineHeight),
+				colWidth-12, lineHeight, highlight, false)
+		}
+	}
+	ebitenutil.DebugPrintAt(screen, strings.Join(scores, "\n"), x, y)
+	ebitenutil.DebugPrintAt(screen, strings.Join(times, "\n"), x+colWidth, y)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// scores は記録のスコアの並び
+func scores(list []Record) []int {
+	var s []int
+	for _, r := range list {
+		s = append(s, r.Score)
+	}
+	return s
+}
+
+// frames は記録のタイム（フレーム数）の並び
+func frames(list []Record) []int {
+	var f []int
+	for _, r := range list {
+		f = append(f, r.Frames)
+	}
+	return f
+}
+
+func TestRecordsAdd(t *testing.T) {
+	tests := []struct {
+		name                string
+		add                 []Record // 先に入れておく記録
+		r                   Record
+		scoreRank, timeRank int
+		scores, frames      []int
+	}{
+		{
+			name: "first record", r: Record{Score: 100, Frames: 600},
+			scoreRank: 0, timeRank: 0, scores: []int{100}, frames: []int{600},
+		},
+		{
+			name: "higher score first, lower time first",
+			add:  []Record{{Score: 100, Frames: 600}, {Score: 300, Frames: 900}},
+			r:    Record{Score: 200, Frames: 300}, scoreRank: 1, timeRank: 0,
+			scores: []int{300, 200, 100}, frames: []int{300, 600, 900},
+		},
+		{
+			name: "ties go below the earlier record",
+			add:  []Record{{Score: 100, Frames: 600, Initials: "AAA"}},
+			r:    Record{Score: 100, Frames: 600, Initials: "BBB"}, scoreRank: 1, timeRank: 1,
+			scores: []int{100, 100}, frames: []int{600, 600},
+		},
+		{
+			name: "a new best pushes the fifth record off",
+			add: []Record{{Score: 50, Frames: 500}, {Score: 40, Frames: 600}, {Score: 30, Frames: 700},
+				{Score: 20, Frames: 800}, {Score: 10, Frames: 900}},
+			r: Record{Score: 60, Frames: 400}, scoreRank: 0, timeRank: 0,
+			scores: []int{60, 50, 40, 30, 20}, frames: []int{400, 500, 600, 700, 800},
+		},
+		{
+			name: "fifth place is still ranked",
+			add: []Record{{Score: 50, Frames: 500}, {Score: 40, Frames: 600}, {Score: 30, Frames: 700},
+				{Score: 20, Frames: 800}, {Score: 10, Frames: 900}},
+			r: Record{Score: 15, Frames: 850}, scoreRank: 4, timeRank: 4,
+			scores: []int{50, 40, 30, 20, 15}, frames: []int{500, 600, 700, 800, 850},
+		},
+		{
+			name: "too low for a full table",
+			add: []Record{{Score: 50, Frames: 500}, {Score: 40, Frames: 600}, {Score: 30, Frames: 700},
+				{Score: 20, Frames: 800}, {Score: 10, Frames: 900}},
+			r: Record{Score: 10, Frames: 900}, scoreRank: -1, timeRank: -1,
+			scores: []int{50, 40, 30, 20, 10}, frames: []int{500, 600, 700, 800, 900},
+		},
+		{
+			name: "ranked for time only",
+			add: []Record{{Score: 50, Frames: 500}, {Score: 40, Frames: 600}, {Score: 30, Frames: 700},
+				{Score: 20, Frames: 800}, {Score: 10, Frames: 900}},
+			r: Record{Score: 5, Frames: 100}, scoreRank: -1, timeRank: 0,
+			scores: []int{50, 40, 30, 20, 10}, frames: []int{100, 500, 600, 700, 800},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := Records{}
+			for _, r := range tt.add {
+				rs.add("1-1", r)
+			}
+			scoreRank, timeRank := rs.add("1-1", tt.r)
+			if scoreRank != tt.scoreRank || timeRank != tt.timeRank {
+				t.Errorf("ranks = %d, %d; want %d, %d", scoreRank, timeRank, tt.scoreRank, tt.timeRank)
+			}
+			s := rs["1-1"]
+			if got := scores(s.Scores); !slices.Equal(got, tt.scores) {
+				t.Errorf("scores = %v, want %v", got, tt.scores)
+			}
+			if got := frames(s.Times); !slices.Equal(got, tt.frames) {
+				t.Errorf("times = %v, want %v", got, tt.frames)
+			}
+			if scoreRank >= 0 && s.Scores[scoreRank] != tt.r {
+				t.Errorf("score rank %d holds %+v, want the new record", scoreRank, s.Scores[scoreRank])
+			}
+		})
+	}
+}
+
+func TestDecodeRecords(t *testing.T) {
+	rs, err := decodeRecords([]byte(`{"version": 1, "stages": {
+		"1-1": {"scores": [{"score": 300, "frames": 900, "date": "2026-01-02", "initials": "ABC"}],
+		        "times": [{"score": 300, "frames": 900, "date": "2026-01-02", "initials": "ABC"}]},
+		"1-2": null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Record{Score: 300, Frames: 900, Date: "2026-01-02", Initials: "ABC"}
+	if s := rs["1-1"]; s == nil || len(s.Scores) != 1 || s.Scores[0] != want || len(s.Times) != 1 {
+		t.Errorf("1-1 = %+v, want one record %+v", s, want)
+	}
+	if _, ok := rs["1-2"]; ok {
+		t.Error("a null stage was kept")
+	}
+}
+
+func TestDecodeRecordsRejectsCorruptData(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"not json", "records"},
+		{"truncated", `{"version": 1, "stages": {"1-1": {"scores": [`},
+		{"wrong version", `{"version": 2, "stages": {}}`},
+		{"missing version", `{"stages": {}}`},
+		{"wrong type", `{"version": 1, "stages": {"1-1": {"scores": [{"score": "lots"}]}}}`},
+		{"negative score", `{"version": 1, "stages": {"1-1": {"scores": [{"score": -5, "frames": 60}]}}}`},
+		{"zero time", `{"version": 1, "stages": {"1-1": {"times": [{"score": 5, "frames": 0}]}}}`},
+		{"long initials", `{"version": 1, "stages": {"1-1": {"scores": [{"score": 5, "frames": 60, "initials": "ABCD"}]}}}`},
+	}
+	for _, tt := range tests {
+		if _, err := decodeRecords([]byte(tt.data)); err == nil {
+			t.Errorf("%s: decodeRecords succeeded, want error", tt.name)
+		}
+	}
+}
+
+// 保存できる数より多い記録は上位だけ読む
+func TestDecodeRecordsTruncates(t *testing.T) {
+	data := `{"version": 1, "stages": {"1-1": {"scores": [` +
+		`{"score": 6, "frames": 1}, {"score": 5, "frames": 1}, {"score": 4, "frames": 1},` +
+		`{"score": 3, "frames": 1}, {"score": 2, "frames": 1}, {"score": 1, "frames": 1}]}}}`
+	rs, err := decodeRecords([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scores(rs["1-1"].Scores); !slices.Equal(got, []int{6, 5, 4, 3, 2}) {
+		t.Errorf("scores = %v, want the top %d", got, maxRecords)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// リプレイファイルの形式:
+//
+//	"MQRP"                 マジック
+//	uint8                  形式バージョン（replayFormatVersion）
+//	uvarint + bytes        ゲームバージョン
+//	uvarint + bytes        開始ステージ ID（"1-2" など）
+//	uint64 LE              乱数シード
+//	varint                 開始時スコア
+//	uvarint + bytes        プレイヤーの調整値（Physics の JSON。形式バージョン 2 から）
+//	uvarint                総フレーム数
+//	(uint8, uvarint)...    入力ビットと連続フレーム数のランレングス
+const (
+	replayMagic         = "MQRP"
+	replayFormatVersion = 2
+	maxReplayFrames     = 60 * 60 * 60 // 記録・読み込みできる長さ（60fps で 1 時間）
+	maxReplayPhysics    = 1024         // Physics の JSON の最大バイト数
+)
+
+// 入力ビット（1 フレームを 1 バイトに詰める）
+const (
+	inputBitLeft = 1 << iota
+	inputBitRight
+	inputBitJump
+	inputBitPause
+	inputBitAction
+)
+
+// Replay は 1 セッション分の入力記録
+type Replay struct {
+	GameVersion string
+	StageID     string // 記録を始めたステージ（"1-1" など）
+	Seed        uint64
+	StartScore  int
+	Physics     Physics // 記録したときのプレイヤーの調整値
+	Frames      []Input
+}
+
+func packInput(in Input) uint8 {
+	var b uint8
+	if in.Left {
+		b |= inputBitLeft
+	}
+	if in.Right {
+		b |= inputBitRight
+	}
+	if in.Jump {
+		b |= inputBitJump
+	}
+	if in.Pause {
+		b |= inputBitPause
+	}
+	if in.Action {
+		b |= inputBitAction
+	}
+	return b
+}
+
+func unpackInput(b uint8) Input {
+	return Input{
+		Left:   b&inputBitLeft != 0,
+		Right:  b&inputBitRight != 0,
+		Jump:   b&inputBitJump != 0,
+		Pause:  b&inputBitPause != 0,
+		Action: b&inputBitAction != 0,
+	}
+}
+
+// encodeReplay はリプレイをバイナリ形式に変換する
+func encodeReplay(r *Replay) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(replayMagic)
+	buf.WriteByte(replayFormatVersion)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(r.GameVersion))))
+	buf.WriteString(r.GameVersion)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(r.StageID))))
+	buf.WriteString(r.StageID)
+	buf.Write(binary.LittleEndian.AppendUint64(nil, r.Seed))
+	buf.Write(binary.AppendVarint(nil, int64(r.StartScore)))
+	physics, _ := json.Marshal(r.Physics) // 数値だけの構造体なので失敗しない
+	buf.Write(binary.AppendUvarint(nil, uint64(len(physics))))
+	buf.Write(physics)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(r.Frames))))
+
+	// 同じ入力が続くことが多いのでランレングスで詰める
+	for i := 0; i < len(r.Frames); {
+		b := packInput(r.Frames[i])
+		n := 1
+		for i+n < len(r.Frames) && packInput(r.Frames[i+n]) == b {
+			n++
+		}
+		buf.WriteByte(b)
+		buf.Write(binary.AppendUvarint(nil, uint64(n)))
+		i += n
+	}
+	return buf.Bytes()
+}
+
+// decodeReplay はバイナリ形式のリプレイを読み込む
+func decodeReplay(data []byte) (*Replay, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+
+	magic := make([]byte, len(replayMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != replayMagic {
+		return nil, errors.New("replay: not a replay file")
+	}
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	if version < 1 || version > replayFormatVersion {
+		return nil, fmt.Errorf("replay: unsupported format version %d (want %d)", version, replayFormatVersion)
+	}
+
+	readBytes := func(max uint64) ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > max {
+			return nil, fmt.Errorf("too long (%d bytes)", n)
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	readString := func() (string, error) {
+		b, err := readBytes(64)
+		return string(b), err
+	}
+
+	var rp Replay
+	if rp.GameVersion, err = readString(); err != nil {
+		return nil, fmt.Errorf("replay: game version: %w", err)
+	}
+	if rp.StageID, err = readString(); err != nil {
+		return nil, fmt.Errorf("replay: stage id: %w", err)
+	}
+	var seed [8]byte
+	if _, err := io.ReadFull(r, seed[:]); err != nil {
+		return nil, fmt.Errorf("replay: seed: %w", err)
+	}
+	rp.Seed = binary.LittleEndian.Uint64(seed[:])
+	score, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("replay: start score: %w", err)
+	}
+	rp.StartScore = int(score)
+	// 形式バージョン 1 には調整値がないので標準の値で記録したものとして読む。
+	// 後から増えた値がなくても読めるよう、標準の値に上書きする形で読む
+	// （記録したときと同じ動きにするため、調整パネルの範囲や刻みには丸めない）。
+	rp.Physics = defaultPhysics()
+	if version >= 2 {
+		b, err := readBytes(maxReplayPhysics)
+		if err == nil {
+			err = json.Unmarshal(b, &rp.Physics)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay: physics: %w", err)
+		}
+	}
+	total, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("replay: frame count: %w", err)
+	}
+	if total > maxReplayFrames {
+		return nil, fmt.Errorf("replay: too many frames (%d, max %d)", total, maxReplayFrames)
+	}
+	rp.Frames = make([]Input, 0, total) // 壊れたファイルで大きく確保しないよう、上限を確かめてから確保する
+
+	for uint64(len(rp.Frames)) < total {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("replay: frame %d: %w", len(rp.Frames), err)
+		}
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("replay: frame %d: %w", len(rp.Frames), err)
+		}
+		if n == 0 || uint64(len(rp.Frames))+n > total {
+			return nil, fmt.Errorf("replay: frame %d: bad run length %d", len(rp.Frames), n)
+		}
+		in := unpackInput(b)
+		for range n {
+			rp.Frames = append(rp.Frames, in)
+		}
+	}
+	return &rp, nil
+}
+
+// replayInput はリプレイの入力を 1 フレームずつ返す
+type replayInput struct {
+	frames []Input
+	pos    int
+}
+
+func (r *replayInput) Next() Input {
+	if r.pos >= len(r.frames) {
+		return Input{}
+	}
+	in := r.frames[r.pos]
+	r.pos++
+	return in
+}
+
+// done は最後のフレームまで再生したか
+func (r *replayInput) done() bool {
+	return r.pos >= len(r.frames)
+}
+
+// stageID は現在のステージを "1-2" 形式で返す
+func (g *Game) stageID() string {
+	s := g.stages[g.stageIndex]
+	return fmt.Sprintf("%d-%d", s.World, s.Number)
+}
+
+// startRecording は現在のステージの開始時点から入力の記録を始める
+func (g *Game) startRecording() {
+	g.recording = &Replay{
+		GameVersion: gameVersion,
+		StageID:     g.stageID(),
+		Seed:        g.seed,
+		StartScore:  g.score,
+		Physics:     g.physics,
+	}
+}
+
+// recordInput は 1 フレーム分の入力を記録に足す。
+// maxReplayFrames を超えた分は記録しない（保存したファイルを読み込めるように）。
+// 記録の途中で調整パネルから Physics を変えると再生で同じ動きにならないので、そこで記録をやめる。
+func (g *Game) recordInput(in Input) {
+	if g.recording == nil {
+		return
+	}
+	if g.physics != g.recording.Physics {
+		g.recording = nil
+		g.showNotice("RECORDING STOPPED (PHYSICS CHANGED)")
+		return
+	}
+	if len(g.recording.Frames) < maxReplayFrames {
+		g.recording.Frames = append(g.recording.Frames, in)
+	}
+}
+
+// startReplay はリプレイの開始ステージ・シード・スコア・調整値を復元して再生を始める。
+// 再生が終わると元の入力元と調整値に戻る。
+func (g *Game) startReplay(r *Replay) error {
+	index := -1
+	for i, s := range g.stages {
+		if fmt.Sprintf("%d-%d", s.World, s.Number) == r.StageID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("replay: unknown stage %q", r.StageID)
+	}
+	if r.GameVersion != gameVersion {
+		log.Printf("replay: recorded with version %s (running %s); playback may diverge", r.GameVersion, gameVersion)
+	}
+
+	if _, ok := g.input.(*replayInput); !ok {
+		g.liveInput = g.input
+		g.livePhysics = g.physics
+	}
+	g.input = &replayInput{frames: r.Frames}
+	g.physics = r.Physics
+	g.recording = nil // リプレイ再生中は記録しない
+	g.setSeed(r.Seed)
+	g.stageIndex = index
+	g.score = r.StartScore
+	g.lives = initialLives
+	g.nextExtraLife = (r.StartScore/extraLifeScore + 1) * extraLifeScore
+	g.setPower(PowerSmall)
+	g.prevInput = Input{}
+	// どの状態からでも読み込めるよう、遷移表を通さずにステージ紹介へ切り替える
+	g.gameState.reset(StateIntro)
+	g.setScene(&playScene{g: g})
+	g.startStage()
+	return nil
+}
+
+// updateReplay はリプレイ再生の終了と、保存・読み込みのホットキー・要求を処理する
+func (g *Game) updateReplay() {
+	if rp, ok := g.input.(*replayInput); ok && rp.done() {
+		g.input = g.liveInput
+		g.physics = g.livePhysics
+		g.showNotice("REPLAY END")
+	}
+
+	if g.hotkeys {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+			g.saveReplay()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+			openReplay(g)
+		}
+	}
+
+	select {
+	case data := <-g.replayLoads:
+		r, err := decodeReplay(data)
+		if err == nil {
+			err = g.startReplay(r)
+		}
+		if err != nil {
+			log.Print(err)
+			g.showNotice("REPLAY LOAD FAILED")
+		}
+	default:
+	}
+}
+
+// saveReplay は記録中のリプレイを保存（ブラウザではダウンロード）する
+func (g *Game) saveReplay() {
+	if g.recording == nil {
+		g.showNotice("NOTHING TO SAVE")
+		return
+	}
+	name := fmt.Sprintf("mqrio-%s-%s.mqr", g.recording.StageID, time.Now().Format("20060102-150405"))
+	if err := saveReplayFile(name, encodeReplay(g.recording)); err != nil {
+		log.Print(err)
+		g.showNotice("REPLAY SAVE FAILED")
+		return
+	}
+	g.showNotice("REPLAY SAVED")
+}
@@ -0,0 +1,76 @@
+//go:build !js
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+var (
+	recordPath = flag.String("record", "", "終了時にプレイ内容をこのリプレイファイルに保存する（F9 でも保存）")
+	replayPath = flag.String("replay", "", "起動時にこのリプレイファイルを再生する（F8 で再読み込み）")
+)
+
+// lastReplayPath は F8 で読み込むファイル（-replay か、直前に保存したファイル）
+var lastReplayPath string
+
+// setupReplay は -replay が指定されていれば再生を始める
+func setupReplay(g *Game) error {
+	if *replayPath == "" {
+		return nil
+	}
+	lastReplayPath = *replayPath
+	data, err := os.ReadFile(*replayPath)
+	if err != nil {
+		return err
+	}
+	r, err := decodeReplay(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *replayPath, err)
+	}
+	return g.startReplay(r)
+}
+
+// closeReplay は -record が指定されていれば終了時に記録を保存する
+func closeReplay(g *Game) {
+	if *recordPath == "" || g.recording == nil {
+		return
+	}
+	if err := os.WriteFile(*recordPath, encodeReplay(g.recording), 0o644); err != nil {
+		log.Print(err)
+	}
+}
+
+// saveReplayFile はリプレイをファイルに書き出す。-record があればそちらに上書きする。
+func saveReplayFile(name string, data []byte) error {
+	if *recordPath != "" {
+		name = *recordPath
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return err
+	}
+	lastReplayPath = name
+	log.Printf("replay saved to %s", name)
+	return nil
+}
+
+// openReplay は lastReplayPath のリプレイを読み込んで再生を始める
+func openReplay(g *Game) {
+	if lastReplayPath == "" {
+		g.showNotice("NO REPLAY FILE")
+		return
+	}
+	data, err := os.ReadFile(lastReplayPath)
+	if err != nil {
+		log.Print(err)
+		g.showNotice("REPLAY LOAD FAILED")
+		return
+	}
+	select {
+	case g.replayLoads <- data:
+	default:
+	}
+}
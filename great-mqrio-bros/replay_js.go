@@ -0,0 +1,65 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// setupReplay はブラウザでは何もしない（読み込みは F8 のファイル選択から）
+func setupReplay(g *Game) error {
+	return nil
+}
+
+// closeReplay はブラウザでは何もしない
+func closeReplay(g *Game) {}
+
+// saveReplayFile はリプレイを Blob にしてダウンロードさせる
+func saveReplayFile(name string, data []byte) error {
+	array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(array, data)
+	blob := js.Global().Get("Blob").New([]any{array}, map[string]any{"type": "application/octet-stream"})
+	url := js.Global().Get("URL").Call("createObjectURL", blob)
+	defer js.Global().Get("URL").Call("revokeObjectURL", url)
+
+	doc := js.Global().Get("document")
+	a := doc.Call("createElement", "a")
+	a.Set("href", url)
+	a.Set("download", name)
+	doc.Get("body").Call("appendChild", a)
+	a.Call("click")
+	a.Call("remove")
+	return nil
+}
+
+// openReplay はファイル選択ダイアログを開き、選ばれたリプレイを次のフレームで再生する
+func openReplay(g *Game) {
+	doc := js.Global().Get("document")
+	input := doc.Call("createElement", "input")
+	input.Set("type", "file")
+	input.Set("accept", ".mqr")
+
+	var onChange, onLoad js.Func
+	onChange = js.FuncOf(func(this js.Value, args []js.Value) any {
+		onChange.Release()
+		files := input.Get("files")
+		if files.Length() == 0 {
+			return nil
+		}
+		onLoad = js.FuncOf(func(this js.Value, args []js.Value) any {
+			onLoad.Release()
+			array := js.Global().Get("Uint8Array").New(args[0])
+			data := make([]byte, array.Length())
+			js.CopyBytesToGo(data, array)
+			select {
+			case g.replayLoads <- data:
+			default:
+			}
+			return nil
+		})
+		files.Index(0).Call("arrayBuffer").Call("then", onLoad)
+		return nil
+	})
+	input.Call("addEventListener", "change", onChange)
+	input.Call("click")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestReplayRoundTrip(t *testing.T) {
+	want := &Replay{
+		GameVersion: gameVersion,
+		StageID:     "1-2",
+		Seed:        0xdeadbeef,
+		StartScore:  1234,
+		Physics:     defaultPhysics(),
+		Frames:      append(hold(Input{Right: true}, 100), append(hold(Input{Jump: true, Action: true}, 3), hold(Input{}, 50)...)...),
+	}
+	want.Physics.JumpPower = -16
+	want.Physics.CoyoteFrames = 10
+	got, err := decodeReplay(encodeReplay(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeReplay(encodeReplay(r)) = %+v, want %+v", got, want)
+	}
+}
+
+// header は入力の前までのリプレイファイル（総フレーム数は total、調整値は標準のまま）
+func header(total uint64) []byte {
+	b := []byte(replayMagic)
+	b = append(b, replayFormatVersion, 0, 3)
+	b = append(b, "1-1"...)
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.AppendVarint(b, 0)
+	b = append(b, 2)
+	b = append(b, "{}"...)
+	return binary.AppendUvarint(b, total)
+}
+
+// 形式バージョン 1 のファイルは調整値がないので標準の値で読む
+func TestDecodeReplayVersion1(t *testing.T) {
+	b := []byte(replayMagic)
+	b = append(b, 1, 0, 3)
+	b = append(b, "1-1"...)
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.AppendVarint(b, 0)
+	b = binary.AppendUvarint(b, 5)
+	b = append(b, inputBitRight, 5)
+	r, err := decodeReplay(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Physics != defaultPhysics() || len(r.Frames) != 5 {
+		t.Errorf("physics %+v, %d frames; want default physics and 5 frames", r.Physics, len(r.Frames))
+	}
+}
+
+// 調整値を変えて記録したリプレイを、標準の値のゲームで再生しても同じ動きになる
+func TestReplayRestoresPhysics(t *testing.T) {
+	frames := hold(Input{}, stageIntroFrames+10)
+	frames = append(frames, hold(Input{Right: true}, 10)...)
+	frames = append(frames, hold(Input{Right: true, Jump: true}, 20)...)
+	frames = append(frames, hold(Input{Right: true}, 30)...)
+
+	a := newTestGame(t, frames...)
+	a.physics.JumpPower = -16
+	a.resetToStart() // 変えた調整値で記録し直す（ステージ紹介から）
+	run(t, a, len(frames))
+	if a.recording == nil || len(a.recording.Frames) != len(frames) {
+		t.Fatalf("recording = %v, want %d frames", a.recording, len(frames))
+	}
+	r, err := decodeReplay(encodeReplay(a.recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newTestGame(t)
+	b.physics.Gravity = 0.3 // 再生前の調整値は、再生が終わると戻る
+	live := b.physics
+	if err := b.startReplay(r); err != nil {
+		t.Fatal(err)
+	}
+	run(t, b, len(frames))
+	if b.physics.JumpPower != -16 {
+		t.Errorf("replay runs with jumpPower %v, want -16", b.physics.JumpPower)
+	}
+	if a.player.x != b.player.x || a.player.y != b.player.y || a.score != b.score {
+		t.Errorf("replay diverged: (%v, %v, %d) vs (%v, %v, %d)",
+			a.player.x, a.player.y, a.score, b.player.x, b.player.y, b.score)
+	}
+	run(t, b, 1)
+	if b.physics != live {
+		t.Errorf("physics after the replay = %+v, want %+v", b.physics, live)
+	}
+}
+
+// 記録の途中で調整値を変えると記録をやめる
+func TestPhysicsChangeStopsRecording(t *testing.T) {
+	g := newTestGame(t)
+	run(t, g, 10)
+	g.physics.JumpPower = -16
+	run(t, g, 1)
+	if g.recording != nil {
+		t.Errorf("still recording %d frames after changing physics", len(g.recording.Frames))
+	}
+}
+
+func TestDecodeReplayRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", []byte("NOPE")},
+		{"truncated frames", append(header(10), 0, 5)},
+		{"run past total", append(header(10), 0, 11)},
+		{"zero run", append(header(10), 0, 0)},
+		{"huge frame count", binary.AppendUvarint(append(header(1<<40), 0), 1<<40)},
+		{"just over the limit", header(maxReplayFrames + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r, err := decodeReplay(tt.data); err == nil {
+				t.Errorf("decodeReplay succeeded with %d frames, want error", len(r.Frames))
+			}
+		})
+	}
+}
+
+func TestRecordInputStopsAtLimit(t *testing.T) {
+	g := &Game{recording: &Replay{Frames: make([]Input, maxReplayFrames-1)}}
+	g.recordInput(Input{Left: true})
+	g.recordInput(Input{Right: true})
+	if n := len(g.recording.Frames); n != maxReplayFrames {
+		t.Fatalf("recorded %d frames, want %d", n, maxReplayFrames)
+	}
+	if _, err := decodeReplay(encodeReplay(g.recording)); err != nil {
+		t.Errorf("a recording at the limit does not load: %v", err)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Scene は積み重ねて使う画面の 1 枚。Update は一番上のシーンだけを進め、
+// Draw は一番上から数えて最初の不透明なシーンから上を順に描く（ポーズメニューの下にステージが見える）。
+// メニューの操作も Input で行うので、ポーズ中の操作もリプレイに記録される。
+type Scene interface {
+	update(in, prev Input) error
+	draw(screen *ebiten.Image)
+	opaque() bool // 下のシーンを隠すか
+}
+
+// pushScene は s を一番上に積む
+func (g *Game) pushScene(s Scene) {
+	g.scenes = append(g.scenes, s)
+}
+
+// popScene は一番上のシーンを取り除く（一番下のシーンは残す）
+func (g *Game) popScene() {
+	if len(g.scenes) > 1 {
+		g.scenes = g.scenes[:len(g.scenes)-1]
+	}
+}
+
+// setScene はシーンをすべて s 1 枚に入れ替える
+func (g *Game) setScene(s Scene) {
+	clear(g.scenes)
+	g.scenes = append(g.scenes[:0], s)
+}
+
+// topScene は一番上のシーン
+func (g *Game) topScene() Scene {
+	return g.scenes[len(g.scenes)-1]
+}
+
+// visibleScenes は描くシーンを下から順に返す
+func (g *Game) visibleScenes() []Scene {
+	i := len(g.scenes) - 1
+	for i > 0 && !g.scenes[i].opaque() {
+		i--
+	}
+	return g.scenes[i:]
+}
+
+// menuMove は左右を押した瞬間に n 個の選択肢の choice を 1 つ動かす（端から反対の端へ回る）
+func menuMove(choice, n int, in, prev Input) int {
+	switch {
+	case in.Left && !prev.Left:
+		return (choice + n - 1) % n
+	case in.Right && !prev.Right:
+		return (choice + 1) % n
+	}
+	return choice
+}
+
+// menuText は見出しと、選んでいるものに > を付けた選択肢を並べる
+func menuText(title string, items []string, choice int) string {
+	var b strings.Builder
+	b.WriteString(title + "\n\n")
+	for i, item := range items {
+		if i == choice {
+			b.WriteString("> " + item + "\n")
+		} else {
+			b.WriteString("  " + item + "\n")
+		}
+	}
+	return b.String()
+}
+
+// menuHint はメニューの操作説明（今の割り当てのキーで書く）
+func (g *Game) menuHint(back string) string {
+	b := g.bindings
+	hint := fmt.Sprintf("%s/%s = select, %s = OK", b.firstKey(ControlLeft), b.firstKey(ControlRight), b.firstKey(ControlJump))
+	if back != "" {
+		hint += fmt.Sprintf(", %s = %s", b.firstKey(ControlPause), back)
+	}
+	return hint
+}
+
+// dimScreen は下のシーンを暗くする
+func dimScreen(screen *ebiten.Image, alpha uint8) {
+	vector.DrawFilledRect(screen, 0, 0, screenWidth, screenHeight, color.RGBA{A: alpha}, false)
+}
+
+// titleScene はタイトル画面
+type titleScene struct {
+	g      *Game
+	choice int
+}
+
+var titleItems = []string{"START", "STAGE SELECT", "SETTINGS"}
+
+func (s *titleScene) opaque() bool { return true }
+
+func (s *titleScene) update(in, prev Input) error {
+	s.choice = menuMove(s.choice, len(titleItems), in, prev)
+	if !in.Jump || prev.Jump {
+		return nil
+	}
+	switch s.choice {
+	case 0:
+		s.g.startGame(0)
+	case 1:
+		s.g.pushScene(&stageSelectScene{g: s.g})
+	case 2:
+		s.g.pushScene(&settingsScene{g: s.g})
+	}
+	return nil
+}
+
+func (s *titleScene) draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{A: 255})
+	msg := menuText("GREAT MQRIO BROS.", titleItems, s.choice) + "\n" + s.g.menuHint("") + "\n\nF2 = controls  F7 = audio"
+	ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-80, screenHeight/2-60)
+}
+
+// stageSelectScene は始めるステージを選ぶ画面
+type stageSelectScene struct {
+	g      *Game
+	choice int
+}
+
+func (s *stageSelectScene) opaque() bool { return true }
+
+func (s *stageSelectScene) update(in, prev Input) error {
+	if in.Pause && !prev.Pause {
+		s.g.popScene()
+		return nil
+	}
+	s.choice = menuMove(s.choice, len(s.g.stages), in, prev)
+	if in.Jump && !prev.Jump {
+		s.g.startGame(s.choice)
+	}
+	return nil
+}
+
+func (s *stageSelectScene) draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{A: 255})
+	items := make([]string, len(s.g.stages))
+	for i, st := range s.g.stages {
+		items[i] = fmt.Sprintf("WORLD %d-%d  %s", st.World, st.Number, st.Level.Name)
+	}
+	msg := menuText("STAGE SELECT", items, s.choice) + "\n" + s.g.menuHint("back")
+	ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-100, screenHeight/2-60)
+}
+
+// playScene はステージを遊ぶ画面（ステージ紹介・プレイ中・やられ・ゲームオーバー）。
+// 中の流れは gameState の状態機械が決める。
+type playScene struct {
+	g *Game
+}
+
+func (s *playScene) opaque() bool { return true }
+
+func (s *playScene) update(in, prev Input) error { return s.g.updatePlay(in, prev) }
+
+func (s *playScene) draw(screen *ebiten.Image) { s.g.drawPlay(screen) }
+
+// pauseScene はポーズメニュー。積んでいる間はステージが進まず、BGM と効果音も止まる。
+type pauseScene struct {
+	g      *Game
+	choice int
+}
+
+var pauseItems = []string{"RESUME", "SETTINGS", "QUIT TO TITLE"}
+
+// pauseGame はステージを止めてポーズメニューを開く
+func (g *Game) pauseGame() {
+	g.pauseMusic()
+	if g.sounds != nil {
+		g.sounds.pause()
+	}
+	g.pushScene(&pauseScene{g: g})
+}
+
+// resumeGame はポーズメニューを閉じて、止めたところから続ける
+func (g *Game) resumeGame() {
+	g.popScene()
+	g.playMusic()
+	if g.sounds != nil {
+		g.sounds.resume()
+	}
+}
+
+func (s *pauseScene) opaque() bool { return false }
+
+func (s *pauseScene) update(in, prev Input) error {
+	if in.Pause && !prev.Pause {
+		s.g.resumeGame()
+		return nil
+	}
+	s.choice = menuMove(s.choice, len(pauseItems), in, prev)
+	if !in.Jump || prev.Jump {
+		return nil
+	}
+	switch s.choice {
+	case 0:
+		s.g.resumeGame()
+	case 1:
+		s.g.pushScene(&settingsScene{g: s.g})
+	case 2:
+		if s.g.sounds != nil {
+			s.g.sounds.resume() // 止めていた効果音の残りはそのまま鳴らし切る
+		}
+		s.g.goToTitle()
+	}
+	return nil
+}
+
+func (s *pauseScene) draw(screen *ebiten.Image) {
+	dimScreen(screen, 120)
+	msg := menuText("PAUSED", pauseItems, s.choice) + "\n" + s.g.menuHint("resume")
+	ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-90, screenHeight/2-50)
+}
+
+// goToTitle はゲームをやめてタイトル画面に戻る
+func (g *Game) goToTitle() {
+	g.setState(StateTitle)
+	g.setScene(&titleScene{g: g})
+}
+
+// settingsScene は音量の設定画面（タイトルとポーズメニュー、F7 からどこでも開く）。
+// 左右で項目を選び、ジャンプで上げ、アクションで下げる。閉じるときに保存する。
+//
+// F7 で開いたとき（hotkey）はゲームの流れの外の画面なので、入力はリプレイに記録せず、
+// リプレイ再生中も再生の入力を進めずにプレイヤーの入力で操作する。
+type settingsScene struct {
+	g      *Game
+	choice int
+	hotkey bool
+	prev   Input // F7 で開いたときの前のフレームの入力（ゲームの prevInput とは別に持つ）
+}
+
+// toggleSettings は F7 の設定画面を開く。F7 で開いた設定画面が一番上にあれば閉じる。
+// メニューから開いた設定画面の上には重ねない（操作がリプレイに記録されているので）。
+func (g *Game) toggleSettings() {
+	if s, ok := g.topScene().(*settingsScene); ok {
+		if s.hotkey {
+			s.close()
+		}
+		return
+	}
+	g.pushScene(&settingsScene{g: g, hotkey: true})
+}
+
+func (s *settingsScene) opaque() bool { return false }
+
+func (s *settingsScene) update(in, prev Input) error {
+	g := s.g
+	back := len(mixerRows) // mixerRows の後ろに BACK がある
+	s.choice = menuMove(s.choice, back+1, in, prev)
+	close := in.Pause && !prev.Pause
+	switch {
+	case s.choice == back && in.Jump && !prev.Jump:
+		close = true
+	case s.choice < back && in.Jump && !prev.Jump:
+		g.adjustVolume(s.choice, volumeStep)
+	case s.choice < back && in.Action && !prev.Action:
+		g.adjustVolume(s.choice, -volumeStep)
+	}
+	if close {
+		s.close()
+	}
+	return nil
+}
+
+// close は変えた音量を保存して閉じる
+func (s *settingsScene) close() {
+	g := s.g
+	if g.audioChanged {
+		g.saveAudioSettings()
+		g.audioChanged = false
+	}
+	g.popScene()
+}
+
+func (s *settingsScene) draw(screen *ebiten.Image) {
+	g := s.g
+	dimScreen(screen, 200)
+	items := make([]string, 0, len(mixerRows)+1)
+	for i := range mixerRows {
+		items = append(items, g.mixer.line(i))
+	}
+	items = append(items, "BACK")
+	b := g.bindings
+	msg := menuText("SETTINGS", items, s.choice) + "\n" +
+		fmt.Sprintf("%s/%s = select, %s = up, %s = down, %s = back\n\nF2 = controls",
+			b.firstKey(ControlLeft), b.firstKey(ControlRight), b.firstKey(ControlJump), b.firstKey(ControlAction), b.firstKey(ControlPause))
+	if s.hotkey {
+		msg += "  F7 = close"
+	}
+	ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-150, screenHeight/2-70)
+}
+
+// resultsScene はステージクリア・全ステージクリアの結果画面（クリアしたときに積む）。
+// ステージクリアではそのステージの上位記録も出し、上位に入っていれば次へ進む前にイニシャルを入力する。
+type resultsScene struct {
+	g *Game
+}
+
+func (s *resultsScene) opaque() bool { return s.g.gameState.current == StateAllClear }
+
+func (s *resultsScene) update(in, prev Input) error {
+	g := s.g
+	g.clearTime++
+	if g.gameState.current == StateCleared && g.goal.flagHeight < g.goal.poleHeight-20 {
+		g.goal.flagHeight += 2
+	}
+	if g.clearTime < clearInputDelay || !in.Jump || prev.Jump {
+		return nil
+	}
+	if g.gameState.current == StateAllClear {
+		g.goToTitle()
+		return nil
+	}
+	if g.clearRecord.ranked() {
+		g.pushScene(newInitialsScene(g, in, s.next))
+		return nil
+	}
+	s.next()
+	return nil
+}
+
+// next は次のステージへ進む
+func (s *resultsScene) next() {
+	g := s.g
+	g.nextStage()
+	if g.gameState.current != StateAllClear {
+		g.popScene() // 最後のステージなら続けて全ステージクリアの結果を出す
+	}
+}
+
+func (s *resultsScene) draw(screen *ebiten.Image) {
+	g := s.g
+	stage := g.stages[g.stageIndex]
+	jump := g.bindings.firstKey(ControlJump)
+	if g.gameState.current == StateAllClear {
+		screen.Fill(color.RGBA{A: 255})
+		msg := fmt.Sprintf(
+			"ALL CLEAR!\n\n"+
+				"Total score: %d\n\n"+
+				"[ TITLE ] %s",
+			g.score, jump,
+		)
+		ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-70, screenHeight/2-40)
+		return
+	}
+
+	dimScreen(screen, 180)
+	clearTimeSec := float64(g.clearElapsedFrames) / 60.0
+	remainingCoins := 0
+	for _, c := range g.coins {
+		if !c.collected {
+			remainingCoins++
+		}
+	}
+	coinBonus := remainingCoins * 50
+	next := "[ NEXT STAGE ] " + jump
+	if g.stageIndex+1 >= len(g.stages) {
+		next = "[ FINISH ] " + jump
+	}
+	if g.clearRecord.ranked() {
+		next = "[ ENTER INITIALS ] " + jump
+	}
+	msg := fmt.Sprintf(
+		"STAGE %d-%d CLEAR!\n\n"+
+			"Time: %.2f sec\n"+
+			"Score: %d (this stage %d)\n"+
+			"Coin bonus: %d\n\n"+
+			"%s",
+		stage.World, stage.Number, clearTimeSec, g.score, g.score-g.stageStartScore, coinBonus, next,
+	)
+	ebitenutil.DebugPrintAt(screen, msg, screenWidth/2-100, screenHeight/2-120)
+	if (g.clearRecord.scoreRank == 0 || g.clearRecord.timeRank == 0) && g.clearTime/20%2 == 0 {
+		ebitenutil.DebugPrintAt(screen, "NEW RECORD!", screenWidth/2-100, screenHeight/2-150) // 1 位なら点滅させる
+	}
+	g.drawRecords(screen, screenWidth/2-210, screenHeight/2+20)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2/audio"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/synth"
+)
+
+// SoundEffect は効果音の種類
+type SoundEffect int
+
+const (
+	SoundJump SoundEffect = iota
+	SoundCoin
+	SoundEnemy // 敵を倒した・ダメージを受けた
+	SoundGoal
+	SoundDeath
+	SoundOneUp
+	SoundCheckpoint
+	SoundPowerUp
+	SoundBump // ブロックを下から叩いた
+	SoundBreak
+	soundCount
+)
+
+// soundDef は効果音 1 つの定義
+type soundDef struct {
+	tones  []synth.Tone // 順に鳴らす音
+	voices int          // 同時に重ねて鳴らせる数（足りなければ鳴っている音を止めて鳴らし直す）
+}
+
+// 効果音の音色（短く切る音と、少し余韻を残す音）
+var (
+	blipEnvelope = synth.Envelope{Attack: 0.002, Decay: 0.04, Sustain: 0.7, Release: 0.03}
+	ringEnvelope = synth.Envelope{Attack: 0.002, Decay: 0.15, Sustain: 0.3, Release: 0.15}
+	hitEnvelope  = synth.Envelope{Attack: 0.001, Decay: 0.1}
+)
+
+// arpeggio は freqs を 1 音 step 秒ずつ順に鳴らす音の並びを作る
+func arpeggio(wave synth.Waveform, step float64, freqs ...float64) []synth.Tone {
+	tones := make([]synth.Tone, len(freqs))
+	for i, f := range freqs {
+		tones[i] = synth.Tone{Wave: wave, Duty: 0.25, Freq: f, Duration: step, Envelope: blipEnvelope, Volume: 0.25}
+	}
+	tones[len(tones)-1].Envelope = ringEnvelope
+	return tones
+}
+
+// soundEffects は効果音の一覧。音を変えるときはここだけ直す。
+var soundEffects = [soundCount]soundDef{
+	SoundJump: {voices: 2, tones: []synth.Tone{
+		{Wave: synth.Pulse, Duty: 0.25, Freq: 300, EndFreq: 700, Duration: 0.1, Envelope: blipEnvelope, Volume: 0.25},
+	}},
+	SoundCoin: {voices: 4, tones: []synth.Tone{
+		{Wave: synth.Square, Freq: 988, Duration: 0.05, Envelope: blipEnvelope, Volume: 0.2},
+		{Wave: synth.Square, Freq: 1319, Duration: 0.1, Envelope: ringEnvelope, Volume: 0.2},
+	}},
+	SoundEnemy: {voices: 3, tones: []synth.Tone{
+		{Wave: synth.Square, Freq: 400, EndFreq: 120, Duration: 0.1, Envelope: blipEnvelope, Volume: 0.3},
+	}},
+	SoundGoal: {voices: 1, tones: arpeggio(synth.Pulse, 0.1, 523, 659, 784, 1047, 1319)},
+	SoundDeath: {voices: 1, tones: []synth.Tone{
+		{Wave: synth.Square, Freq: 600, EndFreq: 100, Duration: 0.45, Envelope: blipEnvelope, Volume: 0.3},
+	}},
+	SoundOneUp:      {voices: 1, tones: arpeggio(synth.Square, 0.07, 784, 988, 1175, 1568)},
+	SoundCheckpoint: {voices: 1, tones: arpeggio(synth.Pulse, 0.08, 880, 1175)},
+	SoundPowerUp: {voices: 1, tones: []synth.Tone{
+		{Wave: synth.Pulse, Duty: 0.25, Freq: 262, EndFreq: 1047, Duration: 0.4, Envelope: blipEnvelope, Volume: 0.25},
+	}},
+	SoundBump: {voices: 2, tones: []synth.Tone{
+		{Wave: synth.Triangle, Freq: 180, EndFreq: 90, Duration: 0.08, Envelope: blipEnvelope, Volume: 0.5},
+	}},
+	SoundBreak: {voices: 3, tones: []synth.Tone{
+		{Wave: synth.Noise, Freq: 2000, EndFreq: 400, Duration: 0.12, Envelope: hitEnvelope, Volume: 0.35},
+	}},
+}
+
+// SoundPool は効果音ごとに何本かの audio.Player（ボイス）を持ち、
+// 前の音を止めずに同じ効果音を重ねて鳴らす
+type SoundPool struct {
+	voices [soundCount][]*audio.Player
+	next   [soundCount]int // 空きがないときに鳴らし直すボイス
+	paused []*audio.Player // pause で止めたボイス
+}
+
+// newSoundPool は soundEffects の音を作り、ボイスを用意する
+func newSoundPool(audioContext *audio.Context) *SoundPool {
+	s := &SoundPool{}
+	for e, def := range soundEffects {
+		pcm := synth.Render(audioSampleRate, def.tones...)
+		for range max(def.voices, 1) {
+			s.voices[e] = append(s.voices[e], audioContext.NewPlayerFromBytes(pcm))
+		}
+	}
+	return s
+}
+
+// play は効果音 e を空いているボイスで鳴らす。全部鳴っていればボイスを順番に止めて鳴らし直す。
+func (s *SoundPool) play(e SoundEffect) {
+	voices := s.voices[e]
+	var p *audio.Player
+	for _, v := range voices {
+		if !v.IsPlaying() {
+			p = v
+			break
+		}
+	}
+	if p == nil {
+		p = voices[s.next[e]]
+		s.next[e] = (s.next[e] + 1) % len(voices)
+	}
+	_ = p.Rewind()
+	p.Play()
+}
+
+// setVolume は全部のボイスの音量を変える
+func (s *SoundPool) setVolume(v float64) {
+	for _, voices := range s.voices {
+		for _, p := range voices {
+			p.SetVolume(v)
+		}
+	}
+}
+
+// pause は鳴っているボイスを止める（resume で続きから鳴る）
+func (s *SoundPool) pause() {
+	s.paused = s.paused[:0]
+	for _, voices := range s.voices {
+		for _, p := range voices {
+			if p.IsPlaying() {
+				p.Pause()
+				s.paused = append(s.paused, p)
+			}
+		}
+	}
+}
+
+// resume は pause で止めたボイスを続きから鳴らす
+func (s *SoundPool) resume() {
+	for _, p := range s.paused {
+		p.Play()
+	}
+	s.paused = s.paused[:0]
+}
+
+// playSound は効果音を鳴らす（音声なしで動かしているときは何もしない）
+func (g *Game) playSound(e SoundEffect) {
+	if g.sounds != nil {
+		g.sounds.play(e)
+	}
+}
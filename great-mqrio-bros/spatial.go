@@ -0,0 +1,77 @@
+package main
+
+import "github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+
+const (
+	gridCellSize = 128             // 空間インデックスのセルの大きさ
+	activeMargin = screenWidth / 2 // 画面の外でもこの距離までの敵は動かす（それより遠い敵は止めておく）
+)
+
+func (p *Platform) rect() collision.Rect {
+	return collision.Rect{X: p.x, Y: p.y, W: p.width, H: p.height}
+}
+
+func (e *Enemy) rect() collision.Rect {
+	return collision.Rect{X: e.x, Y: e.y, W: e.width, H: e.height}
+}
+
+func (c *Coin) rect() collision.Rect {
+	return collision.Rect{X: c.x - c.radius, Y: c.y - c.radius, W: c.radius * 2, H: c.radius * 2}
+}
+
+func (p *Player) rect() collision.Rect {
+	return collision.Rect{X: p.x, Y: p.y, W: p.width, H: p.height}
+}
+
+// buildGrids は足場・コイン・敵の空間インデックスを作る（applyLevel から呼ぶ）
+func (g *Game) buildGrids() {
+	g.platformGrid = collision.NewGrid(gridCellSize)
+	for i := range g.platforms {
+		g.platformGrid.Update(i, g.platforms[i].rect())
+	}
+	g.coinGrid = collision.NewGrid(gridCellSize)
+	for i := range g.coins {
+		g.coinGrid.Update(i, g.coins[i].rect())
+	}
+	g.enemyGrid = collision.NewGrid(gridCellSize)
+	g.indexEnemies()
+	g.hazardGrid = collision.NewGrid(gridCellSize)
+	for i := range g.hazards {
+		g.hazardGrid.Update(i, g.hazards[i].rect())
+	}
+}
+
+// indexEnemies は全部の敵の位置を空間インデックスに反映する（位置を戻した後に呼ぶ）
+func (g *Game) indexEnemies() {
+	for i := range g.enemies {
+		g.enemyGrid.Update(i, g.enemies[i].rect())
+	}
+}
+
+// platformsNear は area と重なりうる足場の当たり判定を返す
+// （バッファを使い回すので次の呼び出しまでに使い終えること）
+func (g *Game) platformsNear(area collision.Rect) []collision.Solid {
+	g.nearby = g.platformGrid.Query(area, g.nearby[:0])
+	g.solids = g.solids[:0]
+	for _, i := range g.nearby {
+		p := &g.platforms[i]
+		if !p.isSolid() {
+			continue
+		}
+		g.solids = append(g.solids, collision.Solid{Rect: p.rect(), OneWay: p.kind == PlatformOneWay, ID: i})
+	}
+	return g.solids
+}
+
+// activeArea は敵を動かす範囲（画面とその左右 activeMargin まで）。
+// 遠くの敵まで毎フレーム動かすと、ステージが長くなるほど 1 フレームの手間が増えるので止めておく。
+// 止まるのは見えないところだけなので、画面に入ってくる敵は必ず動いている。
+func (g *Game) activeArea() collision.Rect {
+	return collision.Rect{X: g.cameraX - activeMargin, Y: -screenHeight, W: screenWidth + 2*activeMargin, H: 3 * screenHeight}
+}
+
+// visible は grid に登録されたもののうち画面内にありうるものの添字を返す（描画の間引き用）
+func (g *Game) visible(grid *collision.Grid) []int {
+	g.nearby = grid.Query(collision.Rect{X: g.cameraX, Y: 0, W: screenWidth, H: screenHeight}, g.nearby[:0])
+	return g.nearby
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/keyl0ve/wasm-go-conference-mini-in-sendai-2026/great-mqrio-bros/collision"
+)
+
+// wideLevel は幅 width のステージを作る。300px ごとに足場 1 つ・敵 1 体・コイン 2 枚を並べるので、
+// 中身の数は幅に比例して増える。
+func wideLevel(width float64) *Level {
+	l := &Level{
+		Version:   1,
+		Name:      "wide",
+		Width:     width,
+		Spawn:     LevelPoint{X: 100, Y: 500},
+		Platforms: []LevelPlatform{{X: 0, Y: 550, Width: width, Height: 50}},
+		Goal:      &LevelGoal{X: width - 100, Y: 350, PoleHeight: 200},
+	}
+	for x := 300.0; x+300 < width; x += 300 {
+		l.Platforms = append(l.Platforms, LevelPlatform{X: x, Y: 450, Width: 150, Height: 20})
+		l.Enemies = append(l.Enemies, LevelEnemy{X: x + 100, Y: 526, Width: 24, Height: 24, VX: -2})
+		l.Coins = append(l.Coins, LevelCoin{X: x + 50, Y: 410, Radius: 12}, LevelCoin{X: x + 100, Y: 410, Radius: 12})
+	}
+	return l
+}
+
+// newWideGame は wideLevel(width) のステージの真ん中にプレイヤーを置いたゲームを作る
+func newWideGame(tb testing.TB, width float64) *Game {
+	tb.Helper()
+	g, err := newGame(newScriptedInput(), nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	g.stages = []Stage{{World: 1, Number: 1, Level: wideLevel(width)}}
+	g.resetToStart()
+	g.introTime = stageIntroFrames
+	g.player.x = width / 2
+	g.cameraX = width/2 - screenWidth/2
+	return g
+}
+
+var benchWidths = []float64{2400, 10000, 100000}
+
+// BenchmarkUpdate は 1 フレームの Update の時間。ステージの幅を変えてもほぼ同じになるはず。
+func BenchmarkUpdate(b *testing.B) {
+	for _, w := range benchWidths {
+		b.Run(fmt.Sprintf("%.0fpx", w), func(b *testing.B) {
+			g := newWideGame(b, w)
+			for b.Loop() {
+				g.player.invincible = invincibleFrames // 敵に当たってやられると、やられアニメだけの軽いフレームになる
+				if err := g.Update(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkQueryFrame は 1 フレーム分の空間インデックスの問い合わせ
+// （プレイヤーの周りの足場・コイン・敵、敵を動かす範囲、画面内の描画対象）の時間
+func BenchmarkQueryFrame(b *testing.B) {
+	for _, w := range benchWidths {
+		b.Run(fmt.Sprintf("%.0fpx", w), func(b *testing.B) {
+			g := newWideGame(b, w)
+			area := g.player.rect().Swept(8, 15)
+			for b.Loop() {
+				g.platformsNear(area)
+				g.nearby = g.coinGrid.Query(area, g.nearby[:0])
+				g.enemyBuf = g.enemyGrid.Query(g.activeArea(), g.enemyBuf[:0])
+				g.enemyBuf = g.enemyGrid.Query(g.player.rect(), g.enemyBuf[:0])
+				g.visible(g.platformGrid)
+				g.visible(g.coinGrid)
+				g.visible(g.enemyGrid)
+			}
+		})
+	}
+}
+
+// 画面の外でも activeMargin 以内の敵は動き、それより遠い敵は止まっている
+func TestEnemiesActiveNearCamera(t *testing.T) {
+	g := newWideGame(t, 10000)
+	g.cameraX = 0
+	g.player.x = 100
+	inView := collision.Rect{X: g.cameraX, W: screenWidth}
+	margin := g.activeArea()
+	var moving, frozen int // 画面外で余白の中の敵・余白より遠い敵の添字
+	moving, frozen = -1, -1
+	for i, e := range g.enemies {
+		switch {
+		case e.x > inView.Right() && e.x+e.width < margin.Right() && moving < 0:
+			moving = i
+		case e.x > margin.Right()+300 && frozen < 0:
+			frozen = i
+		}
+	}
+	if moving < 0 || frozen < 0 {
+		t.Fatalf("no enemies to check (moving %d, frozen %d)", moving, frozen)
+	}
+	mx, fx := g.enemies[moving].x, g.enemies[frozen].x
+	run(t, g, 10)
+	if g.enemies[moving].x == mx {
+		t.Errorf("enemy just off screen at x=%v did not move", mx)
+	}
+	if g.enemies[frozen].x != fx {
+		t.Errorf("enemy far off screen moved from %v to %v", fx, g.enemies[frozen].x)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"io/fs"
+	"math"
+	"path"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+//go:generate go run gen_sprites.go
+
+const spriteAtlasPath = "assets/sprites.json"
+
+//go:embed assets/sprites.png assets/sprites.json
+var assetFS embed.FS
+
+// atlasFile はスプライトアトラスの JSON（フレームの切り出し位置とアニメーション）
+type atlasFile struct {
+	Image      string                      `json:"image"` // JSON からの相対パス
+	Frames     map[string]atlasRect        `json:"frames"`
+	Animations map[string][]atlasAnimFrame `json:"animations"`
+}
+
+type atlasRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type atlasAnimFrame struct {
+	Frame    string `json:"frame"`
+	Duration int    `json:"duration"` // 表示するフレーム数（1 以上）
+}
+
+// Animation は名前付きアニメーション。各コマを duration フレームずつ表示してループする。
+type Animation struct {
+	frames    []*ebiten.Image
+	durations []int
+	total     int // 1 ループのフレーム数
+}
+
+// frame は counter フレーム目に表示するコマを返す
+func (a *Animation) frame(counter int) *ebiten.Image {
+	t := counter % a.total
+	if t < 0 {
+		t += a.total
+	}
+	for i, d := range a.durations {
+		if t < d {
+			return a.frames[i]
+		}
+		t -= d
+	}
+	return a.frames[len(a.frames)-1]
+}
+
+// SpriteAtlas は 1 枚の画像から切り出したフレームとアニメーションの集まり
+type SpriteAtlas struct {
+	frames     map[string]*ebiten.Image
+	animations map[string]*Animation
+}
+
+// loadAtlas は fsys の JSON とそこから参照される PNG を読み込む。
+// 存在しないフレームを参照するアニメーションなどはエラーにする。
+func loadAtlas(fsys fs.FS, jsonPath string) (*SpriteAtlas, error) {
+	data, err := fs.ReadFile(fsys, jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	var f atlasFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", jsonPath, err)
+	}
+
+	imgPath := path.Join(path.Dir(jsonPath), f.Image)
+	imgData, err := fs.ReadFile(fsys, imgPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", jsonPath, err)
+	}
+	src, _, err := image.Decode(bytes.NewReader(imgData))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", imgPath, err)
+	}
+
+	sheet := ebiten.NewImageFromImage(src)
+	a := &SpriteAtlas{
+		frames:     make(map[string]*ebiten.Image, len(f.Frames)),
+		animations: make(map[string]*Animation, len(f.Animations)),
+	}
+	for name, r := range f.Frames {
+		rect := image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+		if rect.Empty() || !rect.In(src.Bounds()) {
+			return nil, fmt.Errorf("%s: frames.%s: %v is outside the %v image", jsonPath, name, rect, src.Bounds())
+		}
+		a.frames[name] = sheet.SubImage(rect).(*ebiten.Image)
+	}
+	for name, frames := range f.Animations {
+		if len(frames) == 0 {
+			return nil, fmt.Errorf("%s: animations.%s: no frames", jsonPath, name)
+		}
+		anim := &Animation{}
+		for i, af := range frames {
+			img, ok := a.frames[af.Frame]
+			if !ok {
+				return nil, fmt.Errorf("%s: animations.%s[%d]: unknown frame %q", jsonPath, name, i, af.Frame)
+			}
+			if af.Duration < 1 {
+				return nil, fmt.Errorf("%s: animations.%s[%d]: duration must be at least 1", jsonPath, name, i)
+			}
+			anim.frames = append(anim.frames, img)
+			anim.durations = append(anim.durations, af.Duration)
+			anim.total += af.Duration
+		}
+		a.animations[name] = anim
+	}
+	return a, nil
+}
+
+// frame は名前でフレームを返す。ない場合は nil。
+func (a *SpriteAtlas) frame(name string) *ebiten.Image {
+	return a.frames[name]
+}
+
+// animFrame はアニメーション name の counter フレーム目のコマを返す。ない場合は nil。
+func (a *SpriteAtlas) animFrame(name string, counter int) *ebiten.Image {
+	anim, ok := a.animations[name]
+	if !ok {
+		return nil
+	}
+	return anim.frame(counter)
+}
+
+// drawSprite は img を (x, y) から w×h に引き伸ばして描く。flip なら左右反転する。
+func drawSprite(screen, img *ebiten.Image, x, y, w, h float64, flip bool, tint color.Color) {
+	if img == nil {
+		return
+	}
+	b := img.Bounds()
+	op := &ebiten.DrawImageOptions{}
+	if flip {
+		op.GeoM.Scale(-1, 1)
+		op.GeoM.Translate(float64(b.Dx()), 0)
+	}
+	op.GeoM.Scale(w/float64(b.Dx()), h/float64(b.Dy()))
+	op.GeoM.Translate(x, y)
+	if tint != nil {
+		op.ColorScale.ScaleWithColor(tint)
+	}
+	screen.DrawImage(img, op)
+}
+
+// drawTiled は img を (x, y) から w×h の範囲に敷き詰める。はみ出す分は切り取る。
+// 画面の外になるタイルは描かない（横に長い地面でも画面内の分しか描かない）。
+func drawTiled(screen, img *ebiten.Image, x, y, w, h float64, tint color.Color) {
+	b := img.Bounds()
+	tw, th := float64(b.Dx()), float64(b.Dy())
+	sb := screen.Bounds()
+	startX := max(0, math.Floor((float64(sb.Min.X)-x)/tw)*tw)
+	endX := min(w, float64(sb.Max.X)-x)
+	for ty := 0.0; ty < h; ty += th {
+		for tx := startX; tx < endX; tx += tw {
+			tile := img
+			if tx+tw > w || ty+th > h {
+				r := image.Rect(b.Min.X, b.Min.Y, b.Min.X+int(min(tw, w-tx)), b.Min.Y+int(min(th, h-ty)))
+				if r.Empty() {
+					continue
+				}
+				tile = img.SubImage(r).(*ebiten.Image)
+			}
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(x+tx, y+ty)
+			if tint != nil {
+				op.ColorScale.ScaleWithColor(tint)
+			}
+			screen.DrawImage(tile, op)
+		}
+	}
+}
+
+// playerAnimation はプレイヤーの状態・パワーアップ段階に合うアニメーション名を返す
+func (g *Game) playerAnimation() string {
+	size := "small"
+	switch g.player.power {
+	case PowerBig:
+		size = "big"
+	case PowerFire:
+		size = "fire"
+	}
+	pose := "idle"
+	switch g.player.state.current {
+	case PlayerWalk:
+		pose = "walk"
+	case PlayerSkid:
+		pose = "skid"
+	case PlayerJump, PlayerFall, PlayerStomp:
+		pose = "jump"
+	}
+	return "player-" + size + "-" + pose
+}
+
+// drawSprites はスプライトで足場・コイン・ゴール・敵を描く（drawVector のスプライト版）
+func (g *Game) drawSprites(screen *ebiten.Image, cam float64) {
+	a := g.sprites
+
+	// 足場: 白黒のブロックを敷き詰めて足場の色を掛ける（画面内のものだけ）
+	tiles := map[PlatformKind]*ebiten.Image{
+		PlatformSolid:   a.frame("block"),
+		PlatformOneWay:  a.frame("oneway"),
+		PlatformCrumble: a.frame("crumble"),
+	}
+	for _, i := range g.visible(g.platformGrid) {
+		p := &g.platforms[i]
+		if p.broken {
+			continue
+		}
+		// ブロックは色を掛けずに 1 枚を足場の大きさに合わせて描く
+		var block *ebiten.Image
+		switch {
+		case p.kind == PlatformQuestion && !p.used:
+			block = a.animFrame("question-shine", g.elapsedFrames)
+		case p.kind == PlatformQuestion:
+			block = a.frame("used")
+		case p.kind == PlatformBrick:
+			block = a.frame("brick")
+		}
+		if block != nil {
+			drawSprite(screen, block, p.x-cam, p.y+p.bumpOffset(), p.width, p.height, false, nil)
+			continue
+		}
+		drawTiled(screen, tiles[p.kind], p.x+p.shake()-cam, p.y, p.width, p.height, p.color)
+	}
+
+	coin := a.animFrame("coin-spin", g.elapsedFrames)
+	for _, i := range g.visible(g.coinGrid) {
+		c := g.coins[i]
+		if c.collected {
+			continue
+		}
+		drawSprite(screen, coin, c.x-c.radius-cam, c.y-c.radius, c.radius*2, c.radius*2, false, nil)
+	}
+
+	// ゴール: ポールを縦に並べ、先端に玉、旗は flagHeight の位置
+	drawTiled(screen, a.frame("goal-pole"), g.goal.x-cam, g.goal.y, 8, g.goal.poleHeight, nil)
+	drawSprite(screen, a.frame("goal-top"), g.goal.x-2-cam, g.goal.y-10, 12, 12, false, nil)
+	drawSprite(screen, a.frame("goal-flag"), g.goal.x+8-cam, g.goal.y+g.goal.flagHeight, 24, 16, false, nil)
+
+	g.drawHazards(screen, cam)
+	g.drawCheckpoints(screen, float32(cam))
+	g.drawItems(screen, float32(cam))
+
+	for _, i := range g.visible(g.enemyGrid) {
+		e := g.enemies[i]
+		if !e.isAlive {
+			continue
+		}
+		// 敵ごとに歩くタイミングをずらす
+		t := g.elapsedFrames + int(e.initialX)
+		var img *ebiten.Image
+		var tint color.Color
+		switch {
+		case e.isShell:
+			img = a.frame("shell")
+		case e.kind == EnemyShell:
+			img = a.animFrame("koopa-walk", t)
+		case e.kind == EnemyFlyer:
+			img = a.animFrame("flyer-fly", t)
+		case e.kind == EnemyJumper:
+			img = a.animFrame("enemy-walk", t)
+			tint = color.RGBA{R: 200, G: 150, B: 255, A: 255} // 紫がかった色で普通の敵と見分ける
+		default:
+			img = a.animFrame("enemy-walk", t)
+		}
+		drawSprite(screen, img, e.x-cam, e.y, e.width, e.height, e.vx < 0, tint)
+	}
+}
+
+// drawPlayerSprite はプレイヤーを当たり判定の大きさに合わせてスプライトで描く
+func (g *Game) drawPlayerSprite(screen *ebiten.Image, cam float64) {
+	if g.player.invincible > 0 && g.player.invincible/4%2 == 0 {
+		return
+	}
+	img := g.sprites.animFrame(g.playerAnimation(), int(g.player.animCounter))
+	drawSprite(screen, img, g.player.x-cam, g.player.y, g.player.width, g.player.height,
+		!g.player.isFacingRight, nil)
+}
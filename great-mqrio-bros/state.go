@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+)
+
+// GameState はゲーム全体の状態
+type GameState int
+
+const (
+	StateTitle    GameState = iota // ゲームを始めていない（タイトル・ステージ選択などのメニュー）
+	StateIntro                     // ステージ紹介画面
+	StatePlaying                   // プレイ中（一時停止は pauseScene を積んで止める）
+	StateDying                     // やられアニメーション中
+	StateGameOver                  // ゲームオーバー画面
+	StateCleared                   // ステージクリア画面
+	StateAllClear                  // 全ステージクリア画面
+)
+
+func (s GameState) String() string {
+	switch s {
+	case StateTitle:
+		return "title"
+	case StateIntro:
+		return "intro"
+	case StatePlaying:
+		return "playing"
+	case StateDying:
+		return "dying"
+	case StateGameOver:
+		return "gameover"
+	case StateCleared:
+		return "cleared"
+	case StateAllClear:
+		return "allclear"
+	}
+	return fmt.Sprintf("GameState(%d)", int(s))
+}
+
+// gameTransitions はゲーム状態の遷移表（ここにない遷移は拒否される）。
+// タイトルへはポーズメニューと全ステージクリア画面から戻れる。
+var gameTransitions = map[GameState][]GameState{
+	StateTitle:    {StateIntro},
+	StateIntro:    {StatePlaying},
+	StatePlaying:  {StateDying, StateCleared, StateTitle},
+	StateDying:    {StateIntro, StateGameOver},
+	StateGameOver: {StateIntro},
+	StateCleared:  {StateIntro, StateAllClear},
+	StateAllClear: {StateIntro, StateTitle},
+}
+
+// PlayerState はプレイヤーの状態
+type PlayerState int
+
+const (
+	PlayerIdle  PlayerState = iota // 地面で止まっている
+	PlayerWalk                     // 地面を歩いている
+	PlayerJump                     // 上昇中
+	PlayerFall                     // 落下中
+	PlayerStomp                    // 敵を踏んで跳ね返っている
+	PlayerSkid                     // 地面で向きを変えようとしてブレーキをかけている
+)
+
+func (s PlayerState) String() string {
+	switch s {
+	case PlayerIdle:
+		return "idle"
+	case PlayerWalk:
+		return "walk"
+	case PlayerJump:
+		return "jump"
+	case PlayerFall:
+		return "fall"
+	case PlayerStomp:
+		return "stomp"
+	case PlayerSkid:
+		return "skid"
+	}
+	return fmt.Sprintf("PlayerState(%d)", int(s))
+}
+
+// playerTransitions はプレイヤー状態の遷移表
+var playerTransitions = map[PlayerState][]PlayerState{
+	PlayerIdle:  {PlayerWalk, PlayerJump, PlayerFall, PlayerStomp, PlayerSkid},
+	PlayerWalk:  {PlayerIdle, PlayerJump, PlayerFall, PlayerStomp, PlayerSkid},
+	PlayerJump:  {PlayerFall, PlayerStomp, PlayerIdle, PlayerWalk, PlayerSkid},
+	PlayerFall:  {PlayerIdle, PlayerWalk, PlayerJump, PlayerStomp, PlayerSkid},
+	PlayerStomp: {PlayerFall, PlayerIdle, PlayerWalk, PlayerJump, PlayerSkid},
+	PlayerSkid:  {PlayerIdle, PlayerWalk, PlayerJump, PlayerFall, PlayerStomp},
+}
+
+// stateMachine は遷移表に従って状態を切り替え、状態ごとの enter/exit フックを呼ぶ
+type stateMachine[S interface {
+	~int
+	fmt.Stringer
+}] struct {
+	current     S
+	transitions map[S][]S
+	enter       map[S]func() // 状態に入った直後に呼ばれる
+	exit        map[S]func() // 状態を抜ける直前に呼ばれる
+}
+
+// canTransition は from から to への遷移が遷移表で許可されているか
+func (m *stateMachine[S]) canTransition(from, to S) bool {
+	return slices.Contains(m.transitions[from], to)
+}
+
+// transition は next へ遷移する。同じ状態なら何もしない。
+// 遷移表にない遷移はエラーを返し、状態は変えない。
+func (m *stateMachine[S]) transition(next S) error {
+	if next == m.current {
+		return nil
+	}
+	if !m.canTransition(m.current, next) {
+		return fmt.Errorf("invalid state transition %v -> %v", m.current, next)
+	}
+	m.reset(next)
+	return nil
+}
+
+// reset は遷移表を無視して next に切り替える（ゲーム開始・リプレイ読み込みなどのやり直し用）
+func (m *stateMachine[S]) reset(next S) {
+	if f := m.exit[m.current]; f != nil {
+		f()
+	}
+	m.current = next
+	if f := m.enter[next]; f != nil {
+		f()
+	}
+}
+
+// initStateMachines は Game・Player の状態機械を遷移表とフックで初期化する
+func (g *Game) initStateMachines() {
+	g.gameState = stateMachine[GameState]{
+		current:     StateTitle,
+		transitions: gameTransitions,
+		enter: map[GameState]func(){
+			StateTitle: g.stopMusic,
+			StateIntro: func() {
+				g.introTime = 0
+				g.stopMusic()
+			},
+			StatePlaying: g.playMusic,
+			StateDying:   func() { g.deathTime = 0 },
+			StateGameOver: func() {
+				g.gameOverTime = 0
+				g.gameOverChoice = 0
+			},
+			StateCleared: func() {
+				g.clearTime = 0
+				g.pushScene(&resultsScene{g: g})
+			},
+			StateAllClear: func() { g.clearTime = 0 },
+		},
+		exit: map[GameState]func(){
+			StatePlaying: g.pauseMusic, // やられ・クリア・タイトルへ戻るときは BGM を止める
+		},
+	}
+
+	// 状態が変わったらアニメーションを最初のコマから始める
+	resetAnim := func() { g.player.animCounter = 0 }
+	enter := make(map[PlayerState]func(), len(playerTransitions))
+	for s := range playerTransitions {
+		enter[s] = resetAnim
+	}
+	g.player.state = stateMachine[PlayerState]{
+		current:     PlayerIdle,
+		transitions: playerTransitions,
+		enter:       enter,
+	}
+}
+
+// setState はゲーム状態を遷移させる。不正な遷移はログに出して無視する。
+func (g *Game) setState(next GameState) {
+	if err := g.gameState.transition(next); err != nil {
+		log.Printf("game: %v", err)
+	}
+}
+
+// setPlayerState はプレイヤー状態を遷移させる。不正な遷移はログに出して無視する。
+func (g *Game) setPlayerState(next PlayerState) {
+	if err := g.player.state.transition(next); err != nil {
+		log.Printf("player: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// hookLog は enter/exit フックが呼ばれた順の記録（"exit playing" など）
+type hookLog []string
+
+// newTestMachine は遷移表 transitions の状態機械を from から始め、全状態のフックを log に記録させる
+func newTestMachine[S interface {
+	~int
+	fmt.Stringer
+}](transitions map[S][]S, states []S, from S, log *hookLog) *stateMachine[S] {
+	m := &stateMachine[S]{current: from, transitions: transitions, enter: map[S]func(){}, exit: map[S]func(){}}
+	for _, s := range states {
+		m.enter[s] = func() { *log = append(*log, "enter "+s.String()) }
+		m.exit[s] = func() { *log = append(*log, "exit "+s.String()) }
+	}
+	return m
+}
+
+// checkTable は全状態の組について、遷移表にある遷移は成功してフックを exit → enter の順に呼び、
+// ない遷移はエラーになって状態もフックも変わらないことを確かめる
+func checkTable[S interface {
+	~int
+	fmt.Stringer
+}](t *testing.T, transitions map[S][]S, states []S) {
+	t.Helper()
+	for _, from := range states {
+		for _, to := range states {
+			if from == to {
+				continue
+			}
+			var log hookLog
+			m := newTestMachine(transitions, states, from, &log)
+			err := m.transition(to)
+			if slices.Contains(transitions[from], to) {
+				if err != nil {
+					t.Errorf("%v -> %v: %v", from, to, err)
+				}
+				if m.current != to {
+					t.Errorf("%v -> %v: current = %v", from, to, m.current)
+				}
+				if want := (hookLog{"exit " + from.String(), "enter " + to.String()}); !slices.Equal(log, want) {
+					t.Errorf("%v -> %v: hooks %v, want %v", from, to, log, want)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("%v -> %v: allowed, want error", from, to)
+				}
+				if m.current != from || len(log) != 0 {
+					t.Errorf("%v -> %v: rejected but current = %v, hooks %v", from, to, m.current, log)
+				}
+			}
+		}
+	}
+}
+
+var allGameStates = []GameState{StateTitle, StateIntro, StatePlaying, StateDying, StateGameOver, StateCleared, StateAllClear}
+
+var allPlayerStates = []PlayerState{PlayerIdle, PlayerWalk, PlayerJump, PlayerFall, PlayerStomp, PlayerSkid}
+
+func TestGameTransitions(t *testing.T) {
+	checkTable(t, gameTransitions, allGameStates)
+}
+
+func TestPlayerTransitions(t *testing.T) {
+	checkTable(t, playerTransitions, allPlayerStates)
+}
+
+// 遷移表にすべての状態の行がある（状態を足して表を直し忘れていない）
+func TestTransitionTablesCoverAllStates(t *testing.T) {
+	for _, s := range allGameStates {
+		if _, ok := gameTransitions[s]; !ok {
+			t.Errorf("gameTransitions has no row for %v", s)
+		}
+	}
+	for _, s := range allPlayerStates {
+		if _, ok := playerTransitions[s]; !ok {
+			t.Errorf("playerTransitions has no row for %v", s)
+		}
+	}
+}
+
+func TestRejectedTransition(t *testing.T) {
+	tests := []struct {
+		from, to GameState
+	}{
+		{StateGameOver, StateCleared},
+		{StateTitle, StatePlaying},
+		{StateDying, StateCleared},
+		{StateCleared, StatePlaying},
+	}
+	for _, tt := range tests {
+		var log hookLog
+		m := newTestMachine(gameTransitions, allGameStates, tt.from, &log)
+		if err := m.transition(tt.to); err == nil {
+			t.Errorf("%v -> %v: allowed, want error", tt.from, tt.to)
+		}
+		if m.current != tt.from || len(log) != 0 {
+			t.Errorf("%v -> %v: current = %v, hooks %v; want unchanged", tt.from, tt.to, m.current, log)
+		}
+	}
+}
+
+func TestSameStateIsNoop(t *testing.T) {
+	var log hookLog
+	m := newTestMachine(gameTransitions, allGameStates, StatePlaying, &log)
+	if err := m.transition(StatePlaying); err != nil || len(log) != 0 {
+		t.Errorf("playing -> playing: err %v, hooks %v; want no-op", err, log)
+	}
+}
+
+// Game のフックがゲームの値を初期化する
+func TestGameStateHooks(t *testing.T) {
+	g := newTestGame(t)
+	g.gameState.reset(StatePlaying)
+
+	g.deathTime = 99
+	g.setState(StateDying)
+	if g.gameState.current != StateDying || g.deathTime != 0 {
+		t.Errorf("after dying: state %v deathTime %d, want dying 0", g.gameState.current, g.deathTime)
+	}
+
+	g.gameOverTime, g.gameOverChoice = 99, 1
+	g.setState(StateGameOver)
+	if g.gameOverTime != 0 || g.gameOverChoice != 0 {
+		t.Errorf("after gameover: gameOverTime %d choice %d, want 0 0", g.gameOverTime, g.gameOverChoice)
+	}
+
+	g.setState(StateCleared) // gameover -> cleared は拒否される
+	if g.gameState.current != StateGameOver {
+		t.Errorf("gameover -> cleared changed the state to %v", g.gameState.current)
+	}
+
+	g.introTime = 99
+	g.setState(StateIntro)
+	g.setState(StatePlaying)
+	g.clearTime = 99
+	g.setState(StateCleared)
+	if g.introTime != 0 || g.clearTime != 0 {
+		t.Errorf("introTime %d clearTime %d, want 0 0", g.introTime, g.clearTime)
+	}
+	if _, ok := g.topScene().(*resultsScene); !ok {
+		t.Errorf("entering cleared pushed %T, want *resultsScene", g.topScene())
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !js
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// storageDir は設定などを保存するディレクトリ（ユーザーの設定ディレクトリ、取れなければカレントディレクトリ）
+func storageDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(dir, "great-mqrio-bros")
+}
+
+// loadStorage は name で保存したデータを読む。まだ保存されていなければ fs.ErrNotExist になるエラーを返す。
+func loadStorage(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(storageDir(), name))
+}
+
+// saveStorage は data を name で保存する（デスクトップでは storageDir のファイル）
+func saveStorage(name string, data []byte) error {
+	dir := storageDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
@@ -0,0 +1,51 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall/js"
+)
+
+// storageKeyPrefix は localStorage のキーの接頭辞（同じオリジンの他のページと混ざらないように）
+const storageKeyPrefix = "great-mqrio-bros/"
+
+// localStorage はブラウザの localStorage を返す（使えない環境ではエラー）
+func localStorage() (js.Value, error) {
+	ls := js.Global().Get("localStorage")
+	if ls.IsUndefined() || ls.IsNull() {
+		return js.Value{}, errors.New("localStorage is not available")
+	}
+	return ls, nil
+}
+
+// loadStorage は name で保存したデータを読む。まだ保存されていなければ fs.ErrNotExist になるエラーを返す。
+func loadStorage(name string) ([]byte, error) {
+	ls, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+	v := ls.Call("getItem", storageKeyPrefix+name)
+	if v.IsNull() {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return []byte(v.String()), nil
+}
+
+// saveStorage は data を name で保存する（ブラウザでは localStorage。文字列として保存するので JSON などのテキストに使う）
+func saveStorage(name string, data []byte) (err error) {
+	ls, err := localStorage()
+	if err != nil {
+		return err
+	}
+	// 容量超過などは JS の例外になるので、panic をエラーにして返す
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", name, r)
+		}
+	}()
+	ls.Call("setItem", storageKeyPrefix+name, string(data))
+	return nil
+}
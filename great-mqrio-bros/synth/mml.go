@@ -0,0 +1,161 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Note は MML を読んだ結果の 1 音（Freq が 0 なら休符）
+type Note struct {
+	Freq   float64 // Hz
+	Length float64 // 拍（四分音符 = 1）
+	Volume float64 // 0〜1
+}
+
+// noteSemitones は音名 c〜b の、c からの半音の数
+var noteSemitones = map[byte]int{'c': 0, 'd': 2, 'e': 4, 'f': 5, 'g': 7, 'a': 9, 'b': 11}
+
+// ParseMML は MML を音の並びにする。使えるコマンドは
+//
+//	c d e f g a b   音符（続けて + / # でシャープ、- でフラット、数字で音の長さ、. で付点）
+//	r               休符（長さは音符と同じ）
+//	&8              タイ。直前の音符・休符を続く長さ（省くと l の長さ）だけ延ばす（c4&8 は付点四分音符）
+//	o4 > <          オクターブの指定・1 つ上げる・1 つ下げる（0〜8 の範囲）
+//	l8              長さを省いた音符の長さ（8 なら八分音符）
+//	v12             音量（0〜15）
+//	[ ... ]3        かっこの中を 3 回繰り返す（回数を省くと 2 回）
+//
+// 空白と小節線の | は読み飛ばす。
+func ParseMML(mml string) ([]Note, error) {
+	p := &mmlParser{src: strings.ToLower(mml), octave: 4, length: 4, volume: 1}
+	return p.parse(false)
+}
+
+type mmlParser struct {
+	src    string
+	pos    int
+	octave int
+	length int // 長さを省いたときの音符（4 なら四分音符）
+	volume float64
+}
+
+func (p *mmlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("mml: at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// parse は src を読み、nested なら対応する ] まで読んで返す
+func (p *mmlParser) parse(nested bool) ([]Note, error) {
+	var notes []Note
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '|':
+		case c == ']':
+			if !nested {
+				return nil, p.errorf("unexpected ]")
+			}
+			n, ok := p.number()
+			if !ok {
+				n = 2
+			}
+			repeated := make([]Note, 0, len(notes)*n)
+			for range n {
+				repeated = append(repeated, notes...)
+			}
+			return repeated, nil
+		case c == '[':
+			inner, err := p.parse(true)
+			if err != nil {
+				return nil, err
+			}
+			notes = append(notes, inner...)
+		case c == 'o':
+			n, ok := p.number()
+			if !ok || n > 8 {
+				return nil, p.errorf("octave must be 0-8")
+			}
+			p.octave = n
+		case c == '>' || c == '<':
+			if c == '>' {
+				p.octave++
+			} else {
+				p.octave--
+			}
+			if p.octave < 0 || p.octave > 8 {
+				p.pos--
+				return nil, p.errorf("octave must be 0-8")
+			}
+		case c == '&':
+			if len(notes) == 0 {
+				p.pos--
+				return nil, p.errorf("tie without a note before it")
+			}
+			notes[len(notes)-1].Length += p.noteLength()
+		case c == 'l':
+			n, ok := p.number()
+			if !ok || n == 0 {
+				return nil, p.errorf("length must be positive")
+			}
+			p.length = n
+		case c == 'v':
+			n, ok := p.number()
+			if !ok || n > 15 {
+				return nil, p.errorf("volume must be 0-15")
+			}
+			p.volume = float64(n) / 15
+		case c == 'r':
+			notes = append(notes, Note{Length: p.noteLength()})
+		case strings.IndexByte("cdefgab", c) >= 0:
+			semitone := noteSemitones[c]
+			if p.pos < len(p.src) {
+				switch p.src[p.pos] {
+				case '+', '#':
+					semitone++
+					p.pos++
+				case '-':
+					semitone--
+					p.pos++
+				}
+			}
+			midi := (p.octave+1)*12 + semitone
+			notes = append(notes, Note{
+				Freq:   440 * math.Pow(2, float64(midi-69)/12),
+				Length: p.noteLength(),
+				Volume: p.volume,
+			})
+		default:
+			p.pos--
+			return nil, p.errorf("unknown command %q", c)
+		}
+	}
+	if nested {
+		return nil, p.errorf("missing ]")
+	}
+	return notes, nil
+}
+
+// noteLength は音符・休符に続く長さと付点を読んで拍数にする
+func (p *mmlParser) noteLength() float64 {
+	n, ok := p.number()
+	if !ok || n == 0 {
+		n = p.length
+	}
+	beats := 4 / float64(n)
+	for add := beats / 2; p.pos < len(p.src) && p.src[p.pos] == '.'; add /= 2 {
+		beats += add
+		p.pos++
+	}
+	return beats
+}
+
+// number は続く 10 進数を読む（数字がなければ ok が false）
+func (p *mmlParser) number() (n int, ok bool) {
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		n = n*10 + int(p.src[p.pos]-'0')
+		p.pos++
+		ok = true
+	}
+	return n, ok
+}
@@ -0,0 +1,105 @@
+package synth
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// freq は o4 の a を 440Hz とした MIDI ノート番号 midi の周波数
+func freq(midi int) float64 {
+	return 440 * math.Pow(2, float64(midi-69)/12)
+}
+
+func TestParseMML(t *testing.T) {
+	c4, a4 := freq(60), freq(69) // o4 の c と a
+	tests := []struct {
+		name string
+		mml  string
+		want []Note
+	}{
+		{"empty", "", nil},
+		{"default length", "a", []Note{{Freq: a4, Length: 1, Volume: 1}}},
+		{"lengths", "a1 a2 a8 a16", []Note{
+			{Freq: a4, Length: 4, Volume: 1}, {Freq: a4, Length: 2, Volume: 1},
+			{Freq: a4, Length: 0.5, Volume: 1}, {Freq: a4, Length: 0.25, Volume: 1},
+		}},
+		{"dotted", "a4. a4.. a8.", []Note{
+			{Freq: a4, Length: 1.5, Volume: 1}, {Freq: a4, Length: 1.75, Volume: 1}, {Freq: a4, Length: 0.75, Volume: 1},
+		}},
+		{"l sets the default length", "l8 a a2 a", []Note{
+			{Freq: a4, Length: 0.5, Volume: 1}, {Freq: a4, Length: 2, Volume: 1}, {Freq: a4, Length: 0.5, Volume: 1},
+		}},
+		{"sharp and flat", "c+ c# d-", []Note{
+			{Freq: freq(61), Length: 1, Volume: 1}, {Freq: freq(61), Length: 1, Volume: 1}, {Freq: freq(61), Length: 1, Volume: 1},
+		}},
+		{"octave up and down", "c > c < < c", []Note{
+			{Freq: c4, Length: 1, Volume: 1}, {Freq: freq(72), Length: 1, Volume: 1}, {Freq: freq(48), Length: 1, Volume: 1},
+		}},
+		{"octave bounds", "o0 c o8 b", []Note{
+			{Freq: freq(12), Length: 1, Volume: 1}, {Freq: freq(119), Length: 1, Volume: 1},
+		}},
+		{"rest", "r8 a r", []Note{{Length: 0.5}, {Freq: a4, Length: 1, Volume: 1}, {Length: 1}}},
+		{"tie", "a4&8 r4&4 a&", []Note{
+			{Freq: a4, Length: 1.5, Volume: 1}, {Length: 2}, {Freq: a4, Length: 2, Volume: 1},
+		}},
+		{"dotted tie", "a2&4.", []Note{{Freq: a4, Length: 3.5, Volume: 1}}},
+		{"volume", "v0 a v15 a", []Note{{Freq: a4, Length: 1}, {Freq: a4, Length: 1, Volume: 1}}},
+		{"repeat", "[a r]3", []Note{
+			{Freq: a4, Length: 1, Volume: 1}, {Length: 1}, {Freq: a4, Length: 1, Volume: 1}, {Length: 1},
+			{Freq: a4, Length: 1, Volume: 1}, {Length: 1},
+		}},
+		{"repeat twice by default", "[a]", []Note{{Freq: a4, Length: 1, Volume: 1}, {Freq: a4, Length: 1, Volume: 1}}},
+		{"nested repeat", "[[a]2 r]2", []Note{
+			{Freq: a4, Length: 1, Volume: 1}, {Freq: a4, Length: 1, Volume: 1}, {Length: 1},
+			{Freq: a4, Length: 1, Volume: 1}, {Freq: a4, Length: 1, Volume: 1}, {Length: 1},
+		}},
+		// かっこの中は 1 回だけ読んで音を並べ直すので、中のオクターブの変更は繰り返さない（後ろの音には効く）
+		{"octave change inside a repeat applies once", "[c >]2 c", []Note{
+			{Freq: c4, Length: 1, Volume: 1}, {Freq: c4, Length: 1, Volume: 1}, {Freq: freq(72), Length: 1, Volume: 1},
+		}},
+		{"blanks, bars and upper case", " A |\n\tR ", []Note{{Freq: a4, Length: 1, Volume: 1}, {Length: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMML(tt.mml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseMML(%q) = %v, want %v", tt.mml, got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if math.Abs(g.Freq-w.Freq) > 1e-9 || g.Length != w.Length || g.Volume != w.Volume {
+					t.Errorf("ParseMML(%q)[%d] = %+v, want %+v", tt.mml, i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMMLErrors(t *testing.T) {
+	tests := []struct {
+		mml  string
+		want string // エラーメッセージに含まれる文字列
+	}{
+		{"a x", `at 2: unknown command 'x'`},
+		{"o9 c", "octave must be 0-8"},
+		{"o", "octave must be 0-8"},
+		{"o8 >", "at 3: octave must be 0-8"},
+		{"o0 c <", "at 5: octave must be 0-8"},
+		{"l0 a", "length must be positive"},
+		{"v16", "volume must be 0-15"},
+		{"&8 a", "at 0: tie without a note before it"},
+		{"[a r", "missing ]"},
+		{"a ]", "at 3: unexpected ]"},
+		{"[[a]", "missing ]"},
+	}
+	for _, tt := range tests {
+		_, err := ParseMML(tt.mml)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseMML(%q) error = %v, want %q", tt.mml, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package synth
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// mixGain は全チャンネルを足した後に掛ける音量（チャンネルが重なっても割れないように）
+const mixGain = 0.2
+
+// Channel は曲の 1 パート
+type Channel struct {
+	Wave     Waveform
+	Duty     float64 // Pulse のデューティ比（0〜1）
+	Volume   float64 // 0〜1
+	Gate     float64 // 音符の長さのうち鍵を押している割合（0 なら 0.9）。残りはリリースになる
+	Envelope Envelope
+	MML      string
+}
+
+// Song はループ再生する曲
+type Song struct {
+	Tempo    float64 // 1 分あたりの拍数（四分音符）
+	Channels []Channel
+}
+
+// voice は再生中の 1 チャンネルの状態
+type voice struct {
+	ch     Channel
+	notes  []Note
+	starts []float64 // 各音が始まる拍
+	idx    int       // 鳴らしている音（-1 ならまだ・もう鳴っていない）
+	t      float64   // 今の音が始まってからの秒数
+	gate   float64   // 今の音の鍵を離すまでの秒数
+	osc    oscillator
+}
+
+// Sequencer は Song を 16bit LE ステレオの PCM にして返す io.Reader。
+// 曲の終わり（一番長いチャンネルの終わり）まで来ると最初に戻ってずっと鳴り続ける。
+type Sequencer struct {
+	sampleRate int
+	tempo      float64
+	tempoScale atomic.Uint64 // float64 のビット列（再生中に別のゴルーチンから変えられる）
+	voices     []*voice
+	length     float64 // 1 ループの拍数
+	beat       float64 // 今の位置（拍）
+}
+
+// NewSequencer は song の MML を読んで sampleRate Hz で再生するシーケンサーを作る
+func NewSequencer(song Song, sampleRate int) (*Sequencer, error) {
+	if song.Tempo <= 0 {
+		return nil, fmt.Errorf("synth: tempo must be positive (got %v)", song.Tempo)
+	}
+	s := &Sequencer{sampleRate: sampleRate, tempo: song.Tempo}
+	s.SetTempoScale(1)
+	for i, ch := range song.Channels {
+		notes, err := ParseMML(ch.MML)
+		if err != nil {
+			return nil, fmt.Errorf("synth: channel %d: %w", i, err)
+		}
+		if ch.Gate == 0 {
+			ch.Gate = 0.9
+		}
+		v := &voice{ch: ch, notes: notes, starts: make([]float64, len(notes))}
+		end := 0.0
+		for j, n := range notes {
+			v.starts[j] = end
+			end += n.Length
+		}
+		s.length = max(s.length, end)
+		s.voices = append(s.voices, v)
+	}
+	if s.length == 0 {
+		return nil, fmt.Errorf("synth: song has no notes")
+	}
+	s.rewind()
+	return s, nil
+}
+
+// SetTempoScale はテンポを scale 倍にする（残り時間が少ないときに速くするなど）。再生中に呼んでよい。
+func (s *Sequencer) SetTempoScale(scale float64) {
+	s.tempoScale.Store(math.Float64bits(scale))
+}
+
+// TempoScale は今のテンポの倍率
+func (s *Sequencer) TempoScale() float64 {
+	return math.Float64frombits(s.tempoScale.Load())
+}
+
+// rewind は曲の最初に戻る
+func (s *Sequencer) rewind() {
+	s.beat = 0
+	for _, v := range s.voices {
+		v.idx = -1
+	}
+}
+
+// Read は p を PCM で埋める（4 バイト単位。曲はループするので終わらない）
+func (s *Sequencer) Read(p []byte) (int, error) {
+	n := len(p) / 4 * 4
+	dt := 1 / float64(s.sampleRate)
+	for i := 0; i < n; i += 4 {
+		beatsPerSecond := s.tempo * s.TempoScale() / 60
+		mix := 0.0
+		for _, v := range s.voices {
+			mix += v.next(s.beat, beatsPerSecond, s.sampleRate)
+			v.t += dt
+		}
+		sample := int16(math.Max(-1, math.Min(1, mix*mixGain)) * math.MaxInt16)
+		binary.LittleEndian.PutUint16(p[i:], uint16(sample))
+		binary.LittleEndian.PutUint16(p[i+2:], uint16(sample))
+
+		s.beat += beatsPerSecond * dt
+		if s.beat >= s.length {
+			s.rewind()
+		}
+	}
+	return n, nil
+}
+
+// next は拍 beat での v の次の 1 サンプルを返す
+func (v *voice) next(beat, beatsPerSecond float64, sampleRate int) float64 {
+	// beat に来た音まで進める
+	for v.idx+1 < len(v.notes) && beat >= v.starts[v.idx+1] {
+		v.idx++
+		v.t = 0
+		v.gate = v.notes[v.idx].Length * v.ch.Gate / beatsPerSecond
+	}
+	if v.idx < 0 {
+		return 0
+	}
+	note := v.notes[v.idx]
+	if note.Freq == 0 {
+		return 0
+	}
+	level := v.ch.Envelope.level(v.t, v.gate)
+	if level == 0 {
+		return 0
+	}
+	return v.osc.sample(v.ch.Wave, v.ch.Duty, note.Freq, sampleRate) * level * note.Volume * v.ch.Volume
+}
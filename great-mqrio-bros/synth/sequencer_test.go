@@ -0,0 +1,95 @@
+package synth
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testSampleRate は 1 サンプルの秒数が 2 進の小数でちょうど表せる値（拍の位置に誤差がたまらない）
+const testSampleRate = 128
+
+// read は s から n サンプル読み、左チャンネルの値を返す
+func read(t *testing.T, s *Sequencer, n int) []int16 {
+	t.Helper()
+	buf := make([]byte, n*4)
+	if got, err := s.Read(buf); err != nil || got != len(buf) {
+		t.Fatalf("Read = %d, %v; want %d, nil", got, err, len(buf))
+	}
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(buf[i*4:]))
+	}
+	return samples
+}
+
+// silent は samples がすべて 0 か
+func silent(samples []int16) bool {
+	for _, v := range samples {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 一番長いチャンネルの終わりで最初に戻り、短いチャンネルはその間休む
+func TestSequencerLoop(t *testing.T) {
+	// テンポ 60 なら 1 拍 = 1 秒 = 128 サンプル
+	s, err := NewSequencer(Song{Tempo: 60, Channels: []Channel{
+		{Wave: Square, Volume: 1, Gate: 1, Envelope: Envelope{Sustain: 1}, MML: "c4 r4 r4"},
+		{Wave: Square, Volume: 1, Gate: 1, Envelope: Envelope{Sustain: 1}, MML: "r4"},
+	}}, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.length != 3 {
+		t.Fatalf("loop length = %v beats, want 3", s.length)
+	}
+
+	first := read(t, s, 384)
+	if silent(first[:128]) {
+		t.Error("the first beat is silent, want the c")
+	}
+	if !silent(first[128:]) {
+		t.Error("the rests are not silent")
+	}
+	if s.beat != 0 {
+		t.Errorf("beat = %v after one loop, want back at 0", s.beat)
+	}
+
+	second := read(t, s, 384)
+	if silent(second[:128]) || !silent(second[128:]) {
+		t.Error("the second loop does not repeat the first")
+	}
+}
+
+// テンポの倍率を上げると 1 ループが短くなる
+func TestSequencerTempoScale(t *testing.T) {
+	s, err := NewSequencer(Song{Tempo: 60, Channels: []Channel{
+		{Wave: Square, Volume: 1, Gate: 1, Envelope: Envelope{Sustain: 1}, MML: "c4 r4"},
+	}}, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTempoScale(2)
+	samples := read(t, s, 256) // 2 倍の速さなので 2 ループ分
+	if silent(samples[:64]) || !silent(samples[64:128]) || silent(samples[128:192]) || !silent(samples[192:]) {
+		t.Error("at double tempo the loop is not 128 samples long")
+	}
+}
+
+func TestNewSequencerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		song Song
+	}{
+		{"zero tempo", Song{Channels: []Channel{{MML: "c"}}}},
+		{"no notes", Song{Tempo: 120, Channels: []Channel{{MML: ""}}}},
+		{"bad mml", Song{Tempo: 120, Channels: []Channel{{MML: "c"}, {MML: "x"}}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewSequencer(tt.song, testSampleRate); err == nil {
+			t.Errorf("%s: NewSequencer succeeded, want error", tt.name)
+		}
+	}
+}
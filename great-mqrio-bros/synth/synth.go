@@ -0,0 +1,92 @@
+// Package synth はチップチューン風の音を作る小さなシンセサイザー。
+// 矩形波・パルス波・三角波・ノイズの発振器と ADSR エンベロープ、
+// MML で書いた複数チャンネルの曲をループ再生するシーケンサーからなる。
+package synth
+
+import "math"
+
+// Waveform は発振器の波形
+type Waveform int
+
+const (
+	Square   Waveform = iota // 矩形波（デューティ比 50%）
+	Pulse                    // パルス波（デューティ比は Channel.Duty）
+	Triangle                 // 三角波（ベース向き）
+	Noise                    // ノイズ（ドラム向き）。音程は LFSR を進める速さになる
+)
+
+// noiseClockScale はノイズの音程に掛けて LFSR を進める速さにする倍率。
+// 音程そのままだと低すぎてうなりにしか聞こえないため。
+const noiseClockScale = 16
+
+// Envelope は ADSR エンベロープ。Attack・Decay・Release は秒、Sustain は 0〜1 の音量。
+type Envelope struct {
+	Attack  float64
+	Decay   float64
+	Sustain float64
+	Release float64
+}
+
+// level は音が鳴り始めてから t 秒、gate 秒で離鍵したときの音量（0〜1）
+func (e Envelope) level(t, gate float64) float64 {
+	if t >= gate {
+		if e.Release <= 0 {
+			return 0
+		}
+		return e.held(gate) * max(0, 1-(t-gate)/e.Release)
+	}
+	return e.held(t)
+}
+
+// held は鍵を押している間の t 秒での音量
+func (e Envelope) held(t float64) float64 {
+	switch {
+	case t < e.Attack:
+		return t / e.Attack
+	case t < e.Attack+e.Decay:
+		return 1 - (1-e.Sustain)*(t-e.Attack)/e.Decay
+	}
+	return e.Sustain
+}
+
+// oscillator は 1 チャンネル分の発振器の状態
+type oscillator struct {
+	phase float64 // 0〜1
+	lfsr  uint16  // ノイズ用の 15 ビット LFSR
+}
+
+// sample は波形 w・周波数 freq の次の 1 サンプル（-1〜1）を返す
+func (o *oscillator) sample(w Waveform, duty, freq float64, sampleRate int) float64 {
+	step := freq / float64(sampleRate)
+	var v float64
+	switch w {
+	case Square, Pulse:
+		if w == Square {
+			duty = 0.5
+		}
+		v = -1
+		if o.phase < duty {
+			v = 1
+		}
+	case Triangle:
+		v = 4*math.Abs(o.phase-0.5) - 1
+	case Noise:
+		if o.lfsr == 0 {
+			o.lfsr = 1
+		}
+		step *= noiseClockScale
+		// phase が 1 を超えるたびに LFSR を 1 つ進める（ファミコンの長周期ノイズと同じタップ）
+		for o.phase+step >= 1 {
+			bit := (o.lfsr ^ o.lfsr>>1) & 1
+			o.lfsr = o.lfsr>>1 | bit<<14
+			step--
+		}
+		v = -1
+		if o.lfsr&1 == 0 {
+			v = 1
+		}
+	}
+	o.phase += step
+	o.phase -= math.Floor(o.phase)
+	return v
+}
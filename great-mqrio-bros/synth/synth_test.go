@@ -0,0 +1,44 @@
+package synth
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnvelopeLevel(t *testing.T) {
+	e := Envelope{Attack: 0.1, Decay: 0.2, Sustain: 0.5, Release: 0.4}
+	tests := []struct {
+		name    string
+		t, gate float64
+		want    float64
+	}{
+		{"start", 0, 1, 0},
+		{"half way through the attack", 0.05, 1, 0.5},
+		{"end of the attack", 0.1, 1, 1},
+		{"half way through the decay", 0.2, 1, 0.75},
+		{"end of the decay", 0.3, 1, 0.5},
+		{"sustain", 0.8, 1, 0.5},
+		{"release starts at the sustain level", 1, 1, 0.5},
+		{"half way through the release", 1.2, 1, 0.25},
+		{"end of the release", 1.4, 1, 0},
+		{"after the release", 2, 1, 0},
+		{"released during the attack", 0.05, 0.05, 0.5},
+		{"release from the attack level", 0.25, 0.05, 0.25},
+		{"released during the decay", 0.4, 0.2, 0.75 / 2},
+	}
+	for _, tt := range tests {
+		if got := e.level(tt.t, tt.gate); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: level(%v, %v) = %v, want %v", tt.name, tt.t, tt.gate, got, tt.want)
+		}
+	}
+}
+
+// Attack・Decay・Release が 0 なら、押している間は Sustain、離すとすぐ 0 になる
+func TestEnvelopeWithoutRamps(t *testing.T) {
+	e := Envelope{Sustain: 0.8}
+	for _, tt := range []struct{ t, want float64 }{{0, 0.8}, {0.5, 0.8}, {1, 0}, {2, 0}} {
+		if got := e.level(tt.t, 1); got != tt.want {
+			t.Errorf("level(%v, 1) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
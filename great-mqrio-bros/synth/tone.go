@@ -0,0 +1,47 @@
+package synth
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Tone は効果音の 1 音。鳴っている間に周波数を Freq から EndFreq へ滑らかに変えられる。
+type Tone struct {
+	Wave     Waveform
+	Duty     float64 // Pulse のデューティ比（0〜1）
+	Freq     float64 // 鳴り始めの周波数（Hz）
+	EndFreq  float64 // 鳴り終わりの周波数（0 なら Freq のまま）
+	Duration float64 // 鍵を押している秒数（この後 Envelope.Release の分だけ余韻が鳴る）
+	Envelope Envelope
+	Volume   float64 // 0〜1
+}
+
+// Render は tones を順に鳴らした 16bit LE ステレオの PCM を返す
+func Render(sampleRate int, tones ...Tone) []byte {
+	var buf []byte
+	for _, t := range tones {
+		buf = t.render(buf, sampleRate)
+	}
+	return buf
+}
+
+// render は t の PCM を buf に足して返す
+func (t Tone) render(buf []byte, sampleRate int) []byte {
+	total := t.Duration + t.Envelope.Release
+	n := int(total * float64(sampleRate))
+	end := t.EndFreq
+	if end == 0 {
+		end = t.Freq
+	}
+	var osc oscillator
+	for i := range n {
+		sec := float64(i) / float64(sampleRate)
+		// 音程は指数的に変える（耳には一定の速さで上がる・下がるように聞こえる）
+		freq := t.Freq * math.Pow(end/t.Freq, sec/total)
+		v := osc.sample(t.Wave, t.Duty, freq, sampleRate) * t.Envelope.level(sec, t.Duration) * t.Volume
+		sample := int16(math.Max(-1, math.Min(1, v)) * math.MaxInt16)
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(sample))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(sample))
+	}
+	return buf
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// タッチ操作のボタンの配置（画面座標）。十字キーは左下、ジャンプ・ダッシュは右下に置く。
+const (
+	touchPadX, touchPadY   = 20, 430 // 十字キー（左右）の範囲の左上
+	touchPadW, touchPadH   = 220, 150
+	touchJumpX, touchJumpY = 730, 520 // ジャンプボタンの中心
+	touchJumpRadius        = 50
+	touchRunX, touchRunY   = 615, 545 // ダッシュ・ファイアボタンの中心
+	touchRunRadius         = 38
+	touchPauseX            = screenWidth - 50 // 一時停止ボタンの左上
+	touchPauseY            = 40
+	touchPauseSize         = 36
+	touchHitMargin         = 20 // 丸いボタンは見た目より少し広く反応させる
+)
+
+// TouchControls は画面に重ねて描く仮想の十字キーとボタン。
+// 一度でもタッチされたら有効になり、指ごとに（マルチタッチで）どのボタンを押しているか調べる。
+type TouchControls struct {
+	enabled bool
+	ids     []ebiten.TouchID
+	pressed [controlCount]bool
+}
+
+// update は今触れている指から押しているボタンを求める
+func (t *TouchControls) update() {
+	t.ids = ebiten.AppendTouchIDs(t.ids[:0])
+	if len(t.ids) > 0 {
+		t.enabled = true
+	}
+	t.pressed = [controlCount]bool{}
+	for _, id := range t.ids {
+		x, y := ebiten.TouchPosition(id)
+		if c, ok := touchControlAt(float64(x), float64(y)); ok {
+			t.pressed[c] = true
+		}
+	}
+}
+
+// touchControlAt は画面上の点 (x, y) にあるボタンの操作を返す
+func touchControlAt(x, y float64) (Control, bool) {
+	switch {
+	case x >= touchPadX && x < touchPadX+touchPadW && y >= touchPadY && y < touchPadY+touchPadH:
+		// 十字キーは真ん中で左右に分ける（指を滑らせると向きが変わる）
+		if x < touchPadX+touchPadW/2 {
+			return ControlLeft, true
+		}
+		return ControlRight, true
+	case inCircle(x, y, touchJumpX, touchJumpY, touchJumpRadius+touchHitMargin):
+		return ControlJump, true
+	case inCircle(x, y, touchRunX, touchRunY, touchRunRadius+touchHitMargin/2):
+		return ControlAction, true
+	case x >= touchPauseX && x < touchPauseX+touchPauseSize && y >= touchPauseY && y < touchPauseY+touchPauseSize:
+		return ControlPause, true
+	}
+	return 0, false
+}
+
+func inCircle(x, y, cx, cy, r float64) bool {
+	dx, dy := x-cx, y-cy
+	return dx*dx+dy*dy <= r*r
+}
+
+// drawTouch はタッチ操作のボタンを半透明で描く（押しているボタンは濃くする）
+func (g *Game) drawTouch(screen *ebiten.Image) {
+	t := g.touch
+	if t == nil || !t.enabled {
+		return
+	}
+	fill := func(c Control) color.Color {
+		if t.pressed[c] {
+			return color.NRGBA{R: 255, G: 255, B: 255, A: 140}
+		}
+		return color.NRGBA{R: 255, G: 255, B: 255, A: 60}
+	}
+	const half = touchPadW / 2
+	vector.DrawFilledRect(screen, touchPadX, touchPadY, half-4, touchPadH, fill(ControlLeft), false)
+	vector.DrawFilledRect(screen, touchPadX+half+4, touchPadY, half-4, touchPadH, fill(ControlRight), false)
+	drawArrow(screen, touchPadX+half/2, touchPadY+touchPadH/2, -1)
+	drawArrow(screen, touchPadX+half+4+half/2, touchPadY+touchPadH/2, 1)
+
+	vector.DrawFilledCircle(screen, touchJumpX, touchJumpY, touchJumpRadius, fill(ControlJump), true)
+	vector.DrawFilledCircle(screen, touchRunX, touchRunY, touchRunRadius, fill(ControlAction), true)
+	vector.DrawFilledRect(screen, touchPauseX, touchPauseY, touchPauseSize, touchPauseSize, fill(ControlPause), false)
+	// 一時停止ボタンの「||」
+	for _, dx := range []float32{11, 21} {
+		vector.DrawFilledRect(screen, touchPauseX+dx, touchPauseY+9, 4, touchPauseSize-18, color.NRGBA{A: 160}, false)
+	}
+}
+
+// drawArrow は (cx, cy) を中心に dir の向き（-1: 左, 1: 右）の三角形を、先に向かって低くなる縦棒を並べて描く
+func drawArrow(screen *ebiten.Image, cx, cy, dir float32) {
+	const size, bars = 24, 6
+	for i := range bars {
+		h := size * float32(bars-i) / bars
+		x := cx + dir*(float32(i)*size/bars-size/2)
+		if dir < 0 {
+			x -= size / bars
+		}
+		vector.DrawFilledRect(screen, x, cy-h/2, size/bars, h, color.NRGBA{A: 160}, false)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	physicsProfilesFile    = "physics-profiles.json" // saveStorage / loadStorage の名前
+	physicsProfilesVersion = 1
+	defaultProfileName     = "default"
+	maxProfileNameLength   = 16
+)
+
+// physicsProfilesData はプロファイルファイルの形式。
+// 各プロファイルは defaultPhysics に上書きする形で読むので、後から増えた値がなくても読める。
+type physicsProfilesData struct {
+	Version  int                        `json:"version"`
+	Profiles map[string]json.RawMessage `json:"profiles"`
+}
+
+// physicsParam は調整パネルで変えられる値の 1 行
+type physicsParam struct {
+	name     string
+	step     float64 // - / = を 1 回押したときに変わる量
+	min, max float64
+	float    func(p *Physics) *float64 // float と int のどちらか一方を使う
+	int      func(p *Physics) *int
+}
+
+var physicsParams = []physicsParam{
+	{name: "gravity", step: 0.05, min: 0.05, max: 3, float: func(p *Physics) *float64 { return &p.Gravity }},
+	{name: "maxFallSpeed", step: 0.5, min: 1, max: 40, float: func(p *Physics) *float64 { return &p.MaxFallSpeed }},
+	{name: "moveSpeed", step: 0.25, min: 0.25, max: 16, float: func(p *Physics) *float64 { return &p.MoveSpeed }},
+	{name: "runSpeed", step: 0.25, min: 0.25, max: 16, float: func(p *Physics) *float64 { return &p.RunSpeed }},
+	{name: "acceleration", step: 0.05, min: 0.05, max: 16, float: func(p *Physics) *float64 { return &p.Acceleration }},
+	{name: "friction", step: 0.05, min: 0.05, max: 16, float: func(p *Physics) *float64 { return &p.Friction }},
+	{name: "skidDecel", step: 0.05, min: 0.05, max: 16, float: func(p *Physics) *float64 { return &p.SkidDecel }},
+	{name: "jumpPower", step: 0.5, min: -40, max: -1, float: func(p *Physics) *float64 { return &p.JumpPower }},
+	{name: "runJumpBonus", step: 0.25, min: 0, max: 10, float: func(p *Physics) *float64 { return &p.RunJumpBonus }},
+	{name: "jumpCut", step: 0.05, min: 0, max: 1, float: func(p *Physics) *float64 { return &p.JumpCut }},
+	{name: "coyoteFrames", step: 1, min: 0, max: 60, int: func(p *Physics) *int { return &p.CoyoteFrames }},
+	{name: "jumpBufferFrames", step: 1, min: 0, max: 60, int: func(p *Physics) *int { return &p.JumpBufferFrames }},
+}
+
+func (pp *physicsParam) get(p *Physics) float64 {
+	if pp.int != nil {
+		return float64(*pp.int(p))
+	}
+	return *pp.float(p)
+}
+
+// set は v を範囲に収めて p に書き込む（float は step の桁で丸めて誤差がたまらないようにする）
+func (pp *physicsParam) set(p *Physics, v float64) {
+	v = min(max(v, pp.min), pp.max)
+	if pp.int != nil {
+		*pp.int(p) = int(math.Round(v))
+		return
+	}
+	*pp.float(p) = math.Round(v/pp.step) * pp.step
+}
+
+// clampPhysics はファイルから読んだ値などを調整パネルの範囲に収める
+func clampPhysics(p *Physics) {
+	for i := range physicsParams {
+		physicsParams[i].set(p, physicsParams[i].get(p))
+	}
+}
+
+// Tuning は F4 で開く物理の調整パネルの状態
+type Tuning struct {
+	open     bool
+	selected int                // 選んでいる physicsParams の添字
+	profiles map[string]Physics // 保存済みのプロファイル（パネルを初めて開いたときに読む）
+	profile  string             // 保存・読み込みに使うプロファイル名
+	editing  bool               // プロファイル名を入力中
+	name     []rune             // 入力中のプロファイル名
+}
+
+// updateTuning は調整パネルのキー操作を処理する。プロファイル名を入力中でゲームを止めるなら true を返す。
+func (g *Game) updateTuning() bool {
+	t := &g.tuning
+	if !g.hotkeys {
+		return false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF4) {
+		t.open = !t.open
+		t.editing = false
+		if t.open && t.profiles == nil {
+			g.loadPhysicsProfiles()
+		}
+	}
+	if !t.open {
+		return false
+	}
+
+	if t.editing {
+		t.name = ebiten.AppendInputChars(t.name)
+		if len(t.name) > maxProfileNameLength {
+			t.name = t.name[:maxProfileNameLength]
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(t.name) > 0 {
+			t.name = t.name[:len(t.name)-1]
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			if name := strings.TrimSpace(string(t.name)); name != "" {
+				t.profile = name
+			}
+			t.editing = false
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+			t.editing = false // 取り消し
+		}
+		return true
+	}
+
+	pp := &physicsParams[t.selected]
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft):
+		t.selected = (t.selected + len(physicsParams) - 1) % len(physicsParams)
+	case inpututil.IsKeyJustPressed(ebiten.KeyBracketRight):
+		t.selected = (t.selected + 1) % len(physicsParams)
+	case repeatingKey(ebiten.KeyMinus):
+		pp.set(&g.physics, pp.get(&g.physics)-pp.step)
+	case repeatingKey(ebiten.KeyEqual):
+		pp.set(&g.physics, pp.get(&g.physics)+pp.step)
+	case inpututil.IsKeyJustPressed(ebiten.KeyDigit0):
+		def := defaultPhysics()
+		pp.set(&g.physics, pp.get(&def))
+	case inpututil.IsKeyJustPressed(ebiten.KeyComma):
+		t.profile = g.nextProfileName(-1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyPeriod):
+		t.profile = g.nextProfileName(1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		t.editing = true
+		t.name = []rune(t.profile)
+	case inpututil.IsKeyJustPressed(ebiten.KeyF5):
+		g.savePhysicsProfile()
+	case inpututil.IsKeyJustPressed(ebiten.KeyF6):
+		if p, ok := t.profiles[t.profile]; ok {
+			g.physics = p
+			g.showNotice("LOADED " + t.profile)
+		} else {
+			g.showNotice("NO PROFILE " + t.profile)
+		}
+	}
+	return false
+}
+
+// repeatingKey は押した瞬間と、押し続けている間は一定間隔で true になる
+func repeatingKey(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d >= 20 && d%4 == 0)
+}
+
+// nextProfileName は保存済みのプロファイル名を名前順に dir だけ進めた名前を返す
+func (g *Game) nextProfileName(dir int) string {
+	t := &g.tuning
+	names := make([]string, 0, len(t.profiles))
+	for name := range t.profiles {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return t.profile
+	}
+	slices.Sort(names)
+	i, found := slices.BinarySearch(names, t.profile)
+	if !found && dir > 0 {
+		i-- // 見つからなければ i は次の名前を指しているので、1 つ戻してから進める
+	}
+	return names[(i+dir+len(names))%len(names)]
+}
+
+// loadPhysicsProfiles は保存済みのプロファイルを読む。読めなければ空のまま始める。
+func (g *Game) loadPhysicsProfiles() {
+	t := &g.tuning
+	t.profiles = map[string]Physics{}
+	if t.profile == "" {
+		t.profile = defaultProfileName
+	}
+	data, err := loadStorage(physicsProfilesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		t.profiles, err = decodePhysicsProfiles(data)
+	}
+	if err != nil {
+		log.Printf("%s: %v", physicsProfilesFile, err)
+		g.showNotice("PROFILE LOAD FAILED")
+		t.profiles = map[string]Physics{}
+	}
+}
+
+// decodePhysicsProfiles はプロファイルファイルを読み、足りない値を標準の値で埋める
+func decodePhysicsProfiles(data []byte) (map[string]Physics, error) {
+	var f physicsProfilesData
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != physicsProfilesVersion {
+		return nil, fmt.Errorf("unsupported version %d (want %d)", f.Version, physicsProfilesVersion)
+	}
+	profiles := make(map[string]Physics, len(f.Profiles))
+	for name, raw := range f.Profiles {
+		p := defaultPhysics()
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("profiles.%s: %w", name, err)
+		}
+		clampPhysics(&p)
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// savePhysicsProfile は今の値を選んでいるプロファイル名で保存する
+func (g *Game) savePhysicsProfile() {
+	t := &g.tuning
+	t.profiles[t.profile] = g.physics
+
+	f := physicsProfilesData{Version: physicsProfilesVersion, Profiles: map[string]json.RawMessage{}}
+	for name, p := range t.profiles {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		f.Profiles[name] = raw
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err == nil {
+		err = saveStorage(physicsProfilesFile, append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("%s: %v", physicsProfilesFile, err)
+		g.showNotice("PROFILE SAVE FAILED")
+		return
+	}
+	g.showNotice("SAVED " + t.profile)
+}
+
+// drawTuning は調整パネルを画面右上に描く
+func (g *Game) drawTuning(screen *ebiten.Image) {
+	t := &g.tuning
+	if !t.open {
+		return
+	}
+	const x, y, w, lineHeight = 500, 40, 290, 16
+	h := float32((len(physicsParams) + 6) * lineHeight)
+	vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{A: 180}, false)
+
+	var b strings.Builder
+	profile := t.profile
+	if t.editing {
+		profile = string(t.name) + "_"
+	}
+	fmt.Fprintf(&b, "PHYSICS (F4)  profile: %s\n\n", profile)
+	for i := range physicsParams {
+		pp := &physicsParams[i]
+		cursor := "  "
+		if i == t.selected {
+			cursor = "> "
+		}
+		if pp.int != nil {
+			fmt.Fprintf(&b, "%s%-17s %d\n", cursor, pp.name, *pp.int(&g.physics))
+		} else {
+			fmt.Fprintf(&b, "%s%-17s %.2f\n", cursor, pp.name, *pp.float(&g.physics))
+		}
+	}
+	b.WriteString("\n[/] select  -/= adjust (hold to repeat)\n0 default  ,/. profile\n")
+	b.WriteString("TAB rename  F5 save  F6 load")
+	ebitenutil.DebugPrintAt(screen, b.String(), x+8, y+4)
+}